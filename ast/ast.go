@@ -62,6 +62,34 @@ func (c *CommentStatement) String() string {
 	return out.String()
 }
 
+// RoxygenStatement is a roxygen tag that affects the build
+// of a package, e.g.: #' @importFrom stats median
+type RoxygenStatement struct {
+	Token token.Item
+	Tag   string
+	// lines following the tag, continuation lines included
+	Lines []string
+}
+
+func (r *RoxygenStatement) Item() token.Item     { return r.Token }
+func (r *RoxygenStatement) statementNode()       {}
+func (r *RoxygenStatement) TokenLiteral() string { return r.Token.Value }
+func (r *RoxygenStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("#' @" + r.Tag)
+	for i, line := range r.Lines {
+		if i == 0 {
+			out.WriteString(" " + line)
+			continue
+		}
+		out.WriteString("\n#' " + line)
+	}
+	out.WriteString("\n")
+
+	return out.String()
+}
+
 type ExportStatement struct {
 	Token token.Item
 	Value string
@@ -0,0 +1,784 @@
+// Package binder resolves every identifier of the project
+// to the binding it refers to.
+//
+// Scopes follow R semantics: only functions create a new
+// scope, loops and if blocks assign in the enclosing function.
+// The whole scope tree is built before identifiers are resolved
+// so that the result does not depend on the order in which
+// code is traversed.
+package binder
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/devOpifex/obfuscator/ast"
+	"github.com/devOpifex/obfuscator/catalogue"
+	"github.com/devOpifex/obfuscator/diagnostics"
+	"github.com/devOpifex/obfuscator/environment"
+	"github.com/devOpifex/obfuscator/lexer"
+)
+
+type Binder struct {
+	env       *environment.Environment
+	files     lexer.Files
+	ids       int
+	nodes     map[ast.Node]*environment.Binding
+	arguments map[*ast.Argument]*environment.Binding
+	externals map[string]*environment.Binding
+	scopes    map[*ast.FunctionLiteral]*environment.Environment
+	functions map[*environment.Binding]*ast.FunctionLiteral
+	supers    []superAssign
+	warnings  diagnostics.Diagnostics
+
+	// function being declared
+	fn *ast.FunctionLiteral
+
+	// S3 registry, see s3.go
+	generics   map[string]*Generic
+	methods    map[*environment.Binding]*Method
+	declared   map[string]string
+	dispatched map[string]bool
+	dispatch   map[*ast.StringLiteral]*Generic
+	dispatches []dispatchString
+
+	// classes, see classes.go
+	renameClasses  bool
+	classes        map[string]*Class
+	definedClasses map[string]bool
+	classStrings   map[*ast.StringLiteral]string
+	classHandlers  map[*ast.Argument]string
+
+	// R6 members, see r6.go
+	protectMembers bool
+	public         map[string]*Member
+	private        map[string]*Member
+	accesses       map[ast.Node]*Member
+	memberArgs     map[*ast.Argument]*Member
+	memberStrings  map[*ast.StringLiteral]*Member
+	r6Generators   map[*environment.Binding]*r6Class
+	instances      map[*environment.Binding]bool
+
+	// S4, see s4.go
+	s4Classes     map[string]*S4
+	s4Generics    map[string]*S4
+	s4Definitions map[string]*ast.FunctionLiteral
+	slots         map[string]*S4
+	s4Strings     map[*ast.StringLiteral]*S4
+	s4Candidates  []*ast.StringLiteral
+	slotNodes     map[ast.Node]*S4
+	slotArgs      map[*ast.Argument]*S4
+	generators    map[*environment.Binding]bool
+
+	// reference classes, see refclass.go
+	refClasses    map[*ast.CallExpression]*environment.Environment
+	refGenerators map[*environment.Binding]*environment.Environment
+	refNames      map[string]*environment.Environment
+
+	// list elements and columns, see elements.go
+	renameElements    bool
+	protectedElements map[string]bool
+	elements          map[string]*Element
+	elementNodes      map[ast.Node]string
+	elementArgs       map[*ast.Argument]string
+	elementStrings    map[*ast.StringLiteral]string
+
+	// data-masking, see masking.go
+	masking    map[string]Masking
+	piped      map[*ast.CallExpression]bool
+	maskedArgs map[*ast.Argument]string
+	columns    map[ast.Node]string
+	dataTables map[*environment.Binding]bool
+	brackets   []bracket
+
+	// data-masking call or data.table being resolved
+	masked string
+
+	// strings of dynamic lookups, see lookup.go
+	lookups map[*ast.StringLiteral]*environment.Binding
+
+	// exported functions, see exports.go
+	keepExports    bool
+	exports        map[string]bool
+	exportPatterns []*regexp.Regexp
+	exportedFns    map[*ast.FunctionLiteral]bool
+
+	// functions bound through values, see closures.go
+	closures      []closure
+	lists         map[*environment.Binding]*ast.CallExpression
+	functionLists map[*environment.Binding]bool
+	visiting      map[*ast.FunctionLiteral]bool
+
+	// functions reading their formals, see introspection.go
+	introspected map[*ast.FunctionLiteral]bool
+
+	// named arguments collected by dots, see dots.go
+	passthrough map[*ast.Argument]bool
+
+	// local() and new.env(), see environments.go
+	renameEnvs bool
+	locals     map[*ast.CallExpression]*environment.Environment
+	envs       map[*environment.Binding]bool
+	envMembers map[string]*Element
+	envNodes   map[ast.Node]*Element
+	envStrings map[*ast.StringLiteral]*Element
+
+	// resolving with() on an environment
+	maskedEnv bool
+
+	// box modules, see box.go
+	boxUses     map[*ast.CallExpression][]*boxSpec
+	modules     map[*environment.Binding]bool
+	modulePaths map[ast.Node]string
+	attached    map[int]map[string]*environment.Binding
+
+	// index of the file being declared or resolved,
+	// globals each file defines
+	file    int
+	defined map[int]map[string]bool
+}
+
+// dispatchString is the generic named in UseMethod or NextMethod
+type dispatchString struct {
+	name string
+	str  *ast.StringLiteral
+}
+
+// superAssign is the target of a <<- within a scope
+type superAssign struct {
+	name string
+	env  *environment.Environment
+}
+
+var isName = regexp.MustCompile(`^[A-Za-z.][A-Za-z0-9._]*$`)
+
+func New(env *environment.Environment, files lexer.Files) *Binder {
+	b := &Binder{
+		env:       env,
+		files:     files,
+		nodes:     make(map[ast.Node]*environment.Binding),
+		arguments: make(map[*ast.Argument]*environment.Binding),
+		externals: make(map[string]*environment.Binding),
+		scopes:    make(map[*ast.FunctionLiteral]*environment.Environment),
+		functions: make(map[*environment.Binding]*ast.FunctionLiteral),
+
+		generics:   make(map[string]*Generic),
+		methods:    make(map[*environment.Binding]*Method),
+		declared:   make(map[string]string),
+		dispatched: make(map[string]bool),
+		dispatch:   make(map[*ast.StringLiteral]*Generic),
+
+		classes:        make(map[string]*Class),
+		definedClasses: make(map[string]bool),
+		classStrings:   make(map[*ast.StringLiteral]string),
+		classHandlers:  make(map[*ast.Argument]string),
+
+		public:        make(map[string]*Member),
+		private:       make(map[string]*Member),
+		accesses:      make(map[ast.Node]*Member),
+		r6Generators:  make(map[*environment.Binding]*r6Class),
+		instances:     make(map[*environment.Binding]bool),
+		memberArgs:    make(map[*ast.Argument]*Member),
+		memberStrings: make(map[*ast.StringLiteral]*Member),
+
+		s4Classes:     make(map[string]*S4),
+		s4Generics:    make(map[string]*S4),
+		s4Definitions: make(map[string]*ast.FunctionLiteral),
+		slots:         make(map[string]*S4),
+		s4Strings:     make(map[*ast.StringLiteral]*S4),
+		slotNodes:     make(map[ast.Node]*S4),
+		slotArgs:      make(map[*ast.Argument]*S4),
+		generators:    make(map[*environment.Binding]bool),
+
+		refClasses:    make(map[*ast.CallExpression]*environment.Environment),
+		refGenerators: make(map[*environment.Binding]*environment.Environment),
+		refNames:      make(map[string]*environment.Environment),
+
+		protectedElements: make(map[string]bool),
+		elements:          make(map[string]*Element),
+		elementNodes:      make(map[ast.Node]string),
+		elementArgs:       make(map[*ast.Argument]string),
+		elementStrings:    make(map[*ast.StringLiteral]string),
+
+		masking:    make(map[string]Masking),
+		piped:      make(map[*ast.CallExpression]bool),
+		maskedArgs: make(map[*ast.Argument]string),
+		columns:    make(map[ast.Node]string),
+		dataTables: make(map[*environment.Binding]bool),
+
+		lookups: make(map[*ast.StringLiteral]*environment.Binding),
+
+		exports:     make(map[string]bool),
+		exportedFns: make(map[*ast.FunctionLiteral]bool),
+
+		lists:         make(map[*environment.Binding]*ast.CallExpression),
+		functionLists: make(map[*environment.Binding]bool),
+		visiting:      make(map[*ast.FunctionLiteral]bool),
+
+		introspected: make(map[*ast.FunctionLiteral]bool),
+
+		passthrough: make(map[*ast.Argument]bool),
+
+		locals:     make(map[*ast.CallExpression]*environment.Environment),
+		envs:       make(map[*environment.Binding]bool),
+		envMembers: make(map[string]*Element),
+		envNodes:   make(map[ast.Node]*Element),
+		envStrings: make(map[*ast.StringLiteral]*Element),
+
+		boxUses:     make(map[*ast.CallExpression][]*boxSpec),
+		modules:     make(map[*environment.Binding]bool),
+		modulePaths: make(map[ast.Node]string),
+		attached:    make(map[int]map[string]*environment.Binding),
+
+		defined: make(map[int]map[string]bool),
+	}
+
+	for name, m := range dataMasking {
+		b.masking[name] = m
+	}
+
+	return b
+}
+
+// Run binds all the files: assignments of every file
+// are declared first, then identifiers are resolved.
+func (b *Binder) Run() {
+	for i, f := range b.files {
+		b.file = i
+		b.declareRoxygen(f.Ast.Statements)
+
+		for _, s := range f.Ast.Statements {
+			b.declare(s, b.env, environment.Global)
+		}
+	}
+
+	b.registerRefClasses()
+	b.registerData()
+
+	// <<- assigns in the global environment
+	// when no enclosing environment binds the name
+	for _, s := range b.supers {
+		if s.env.GetSuperBinding(s.name) == nil {
+			b.bind(b.env, s.name, environment.Global, false)
+		}
+	}
+
+	b.registerClosures()
+	b.registerInstances()
+	b.registerBox()
+	b.registerS3()
+	b.registerClasses()
+	b.registerS4()
+	b.protectExports()
+	b.protectIntrospected()
+
+	for i, f := range b.files {
+		b.file = i
+		for _, s := range f.Ast.Statements {
+			b.resolve(s, b.env)
+		}
+	}
+
+	// free identifiers are left as is,
+	// masked names must not collide with them
+	for _, e := range b.externals {
+		environment.Reserve(e.Name)
+	}
+}
+
+// Node returns the binding of an identifier, a call,
+// a named function, or a for loop variable.
+func (b *Binder) Node(node ast.Node) *environment.Binding {
+	return b.nodes[node]
+}
+
+// Argument returns the binding of a function parameter
+// or of a named argument matched to a formal of the callee.
+func (b *Binder) Argument(arg *ast.Argument) *environment.Binding {
+	return b.arguments[arg]
+}
+
+// Warnings returns the diagnostics raised while binding,
+// e.g.: a project function masking a base R function.
+func (b *Binder) Warnings() diagnostics.Diagnostics {
+	return b.warnings
+}
+
+// Scope returns the environment created by a function.
+func (b *Binder) Scope(fn *ast.FunctionLiteral) *environment.Environment {
+	return b.scopes[fn]
+}
+
+func (b *Binder) bind(env *environment.Environment, name string, kind environment.Kind, fn bool) *environment.Binding {
+	if existing := env.GetBinding(name, false); existing != nil {
+		existing.Function = existing.Function || fn
+		return existing
+	}
+
+	b.ids++
+	binding := &environment.Binding{
+		ID:       b.ids,
+		Name:     name,
+		Kind:     kind,
+		Function: fn,
+	}
+	env.SetBinding(binding)
+
+	if env == b.env {
+		if b.defined[b.file] == nil {
+			b.defined[b.file] = make(map[string]bool)
+		}
+		b.defined[b.file][name] = true
+	}
+
+	return binding
+}
+
+// external binds a free identifier, the package
+// is looked up in the catalogue of base R symbols.
+func (b *Binder) external(name string) *environment.Binding {
+	pkg, _ := catalogue.Lookup(name)
+	return b.namespaced(pkg, name)
+}
+
+// namespaced binds a symbol of another package, e.g.: dplyr::filter
+func (b *Binder) namespaced(pkg, name string) *environment.Binding {
+	key := name
+	if pkg != "" {
+		key = pkg + "::" + name
+	}
+
+	if existing, ok := b.externals[key]; ok {
+		return existing
+	}
+
+	b.ids++
+	binding := &environment.Binding{
+		ID:      b.ids,
+		Name:    name,
+		Kind:    environment.External,
+		Package: pkg,
+	}
+	b.externals[key] = binding
+
+	return binding
+}
+
+// declare collects the assignments of a scope
+// and creates the scopes of nested functions.
+func (b *Binder) declare(node ast.Node, env *environment.Environment, kind environment.Kind) {
+	switch node := node.(type) {
+	case *ast.ExpressionStatement:
+		if node.Expression != nil {
+			b.declare(node.Expression, env, kind)
+		}
+
+	case *ast.BlockStatement:
+		if node == nil {
+			return
+		}
+		for _, s := range node.Statements {
+			b.declare(s, env, kind)
+		}
+
+	case ast.ExpressionBlock:
+		b.declare(node.Expression, env, kind)
+
+	case *ast.InfixExpression:
+		if b.declareBox(node, env) {
+			return
+		}
+
+		// within brackets = names an argument: dt[, by = group]
+		if ident, ok := node.Left.(*ast.Identifier); ok && isAssign(node.Operator) && len(b.brackets) == 0 {
+			binding := b.bind(env, ident.Value, kind, false)
+			b.declareDataTable(node.Right, binding, env)
+			b.declareClosure(binding, node.Right, env)
+			b.declareEnv(binding, node.Right)
+		}
+
+		if ident, ok := node.Left.(*ast.Identifier); ok && node.Operator == "<<-" {
+			b.supers = append(b.supers, superAssign{name: ident.Value, env: env})
+		}
+
+		b.declareClasses(node)
+		b.declareElements(node)
+		b.declareGenerator(node, env, kind)
+		b.declareR6Generator(node, env)
+
+		if node.Operator == "$" {
+			b.declareRefMethodsCall(node, env)
+		}
+
+		b.declare(node.Left, env, kind)
+		if node.Operator == "[" || node.Operator == "[[" {
+			b.openBracket(node, env)
+		}
+		b.declare(node.Right, env, kind)
+
+	case *ast.PrefixExpression:
+		b.declare(node.Right, env, kind)
+
+	case *ast.PostfixExpression:
+		b.declare(node.Left, env, kind)
+		b.closeBracket(node.Postfix)
+
+	case *ast.FunctionLiteral:
+		// already declared, e.g.: methods of a reference class
+		if _, ok := b.scopes[node]; ok {
+			return
+		}
+
+		if isName.MatchString(node.Name) {
+			binding := b.bind(env, node.Name, kind, true)
+			b.functions[binding] = node
+			b.shadows(node)
+		}
+
+		scope := b.enclose(node, env)
+
+		fn, brackets := b.fn, b.brackets
+		b.fn, b.brackets = node, nil
+
+		for _, p := range node.Parameters {
+			b.declare(p.Value, scope, environment.Local)
+		}
+
+		b.declare(node.Body, scope, environment.Local)
+
+		b.fn, b.brackets = fn, brackets
+
+	case *ast.For:
+		b.bind(env, node.Name, kind, false)
+		b.declare(node.Vector, env, kind)
+		b.declare(node.Value, env, kind)
+
+	case *ast.While:
+		b.declare(node.Statement, env, kind)
+		b.declare(node.Value, env, kind)
+
+	case *ast.IfExpression:
+		b.declare(node.Condition, env, kind)
+		b.declare(node.Consequence, env, kind)
+		b.declare(node.Alternative, env, kind)
+
+	case *ast.CallExpression:
+		if b.declareLocal(node, env) {
+			return
+		}
+
+		if node.Name == "UseMethod" || node.Name == "NextMethod" {
+			b.declareDispatch(node)
+		}
+
+		b.declareClasses(node)
+		b.declareElements(node)
+		b.declareDataTable(node, nil, env)
+
+		if node.Name == "assign" {
+			b.declareAssign(node, env, kind)
+		}
+
+		b.declareIntrospection(node)
+
+		if node.Name == "R6Class" || node.Name == "set" {
+			b.declareR6(node)
+		}
+
+		b.declareS4(node)
+
+		if node.Name == "setRefClass" {
+			b.declareRefClass(node, env)
+		}
+
+		for _, a := range node.Arguments {
+			b.declare(a.Value, env, kind)
+		}
+	}
+}
+
+// shadows warns when a function defined in the project
+// masks a function of base R or of a default package:
+// calls in its scope use (and rename) the project function.
+func (b *Binder) shadows(node *ast.FunctionLiteral) {
+	pkg, ok := catalogue.Lookup(node.Name)
+
+	if !ok {
+		return
+	}
+
+	b.warnings = append(
+		b.warnings,
+		diagnostics.NewWarning(
+			node.Token,
+			fmt.Sprintf("`%v` masks %v::%v", node.Name, pkg, node.Name),
+		),
+	)
+}
+
+func (b *Binder) resolve(node ast.Node, env *environment.Environment) {
+	switch node := node.(type) {
+	case *ast.ExpressionStatement:
+		if node.Expression != nil {
+			b.resolve(node.Expression, env)
+		}
+
+	case *ast.BlockStatement:
+		if node == nil {
+			return
+		}
+		for _, s := range node.Statements {
+			b.resolve(s, env)
+		}
+
+	case ast.ExpressionBlock:
+		b.resolve(node.Expression, env)
+
+	case *ast.Identifier:
+		if b.masked != "" {
+			b.resolveColumn(node, env)
+			return
+		}
+		b.nodes[node] = b.lookup(env, node.Value)
+
+	case *ast.InfixExpression:
+		if b.masked != "" && isEscape(node) {
+			b.resolveEscape(node, env)
+			return
+		}
+		b.resolveInfix(node, env)
+
+	case *ast.PrefixExpression:
+		if b.masked != "" && isEscape(node) {
+			b.resolveEscape(node, env)
+			return
+		}
+		b.resolve(node.Right, env)
+
+	case *ast.PostfixExpression:
+		b.resolve(node.Left, env)
+		b.closeBracket(node.Postfix)
+
+	case *ast.Keyword:
+		b.resolveDotDot(node, env)
+
+	case *ast.FunctionLiteral:
+		b.resolveFunction(node, env)
+
+	case *ast.For:
+		b.nodes[node] = b.lookup(env, node.Name)
+		b.resolve(node.Vector, env)
+		b.resolve(node.Value, env)
+
+	case *ast.While:
+		b.resolve(node.Statement, env)
+		b.resolve(node.Value, env)
+
+	case *ast.IfExpression:
+		b.resolve(node.Condition, env)
+		b.resolve(node.Consequence, env)
+		b.resolve(node.Alternative, env)
+
+	case *ast.CallExpression:
+		b.resolveCall(node, env)
+	}
+}
+
+func (b *Binder) resolveInfix(node *ast.InfixExpression, env *environment.Environment) {
+	switch node.Operator {
+	// pkg::fn refers to another package
+	case "::", ":::":
+		if b.resolveBox(node, env) {
+			return
+		}
+
+		if call, ok := node.Right.(*ast.CallExpression); ok {
+			pkg := ""
+			if ident, ok := node.Left.(*ast.Identifier); ok {
+				pkg = ident.Value
+			}
+			b.nodes[call] = b.namespaced(pkg, call.Name)
+			b.maskArguments(call, pkg)
+			b.resolveLookup(call, env)
+			b.resolveArguments(call, env, nil)
+			b.resolveS4Call(call, nil)
+		}
+		return
+
+	// x$name, the name is not a symbol we resolve
+	// but it may be a member of a class
+	case "$":
+		b.resolveMember(node, env)
+		b.resolveEnvMember(node, env)
+		b.resolveModuleMember(node, env)
+		b.resolveRefNew(node, env)
+		b.resolveR6New(node, env)
+		b.resolve(node.Left, env)
+		if call, ok := node.Right.(*ast.CallExpression); ok {
+			// .self$initFields(balance = 0), a$field("balance")
+			if b.isInstance(node.Left, env) {
+				b.resolveFieldCall(call)
+			}
+			b.resolveArguments(call, env, nil)
+		}
+		return
+
+	// x@name, name is a slot
+	case "@":
+		b.resolveSlot(node)
+		b.resolve(node.Left, env)
+		return
+	}
+
+	if ident, ok := node.Left.(*ast.Identifier); ok && node.Operator == "<<-" {
+		b.nodes[ident] = env.GetSuperBinding(ident.Value)
+		b.resolve(node.Right, env)
+		return
+	}
+
+	// the data of df |> filter(x > 0) is piped
+	if node.Operator == "|>" || node.Operator == "%>%" {
+		b.pipe(node.Right)
+	}
+
+	if node.Operator == "[[" {
+		b.resolveEnvMember(node, env)
+	}
+
+	b.resolve(node.Left, env)
+	if node.Operator == "[" || node.Operator == "[[" {
+		b.openBracket(node, env)
+	}
+	b.resolve(node.Right, env)
+}
+
+func (b *Binder) resolveFunction(node *ast.FunctionLiteral, env *environment.Environment) {
+	if isName.MatchString(node.Name) {
+		b.nodes[node] = b.lookup(env, node.Name)
+	}
+
+	scope, ok := b.scopes[node]
+
+	if !ok {
+		scope = b.enclose(node, env)
+	}
+
+	// symbols of a function are not columns: across(x, function(v) {})
+	masked, brackets := b.masked, b.brackets
+	b.masked, b.brackets = "", nil
+
+	// default values are evaluated in the function's scope
+	for _, p := range node.Parameters {
+		b.resolve(p.Value, scope)
+	}
+
+	b.resolve(node.Body, scope)
+
+	b.masked, b.brackets = masked, brackets
+}
+
+// enclose creates the scope of a function and binds its parameters
+func (b *Binder) enclose(node *ast.FunctionLiteral, env *environment.Environment) *environment.Environment {
+	scope := environment.Enclose(env)
+	b.scopes[node] = scope
+
+	for _, p := range node.Parameters {
+		if p.Name == "" || p.Name == "..." {
+			continue
+		}
+		b.arguments[p] = b.bind(scope, p.Name, environment.Parameter, false)
+	}
+
+	return scope
+}
+
+func (b *Binder) resolveCall(node *ast.CallExpression, env *environment.Environment) {
+	callee := b.attachedBinding(env.GetFunctionBinding(node.Name), node.Name)
+
+	if callee == nil {
+		callee = b.external(node.Name)
+	}
+
+	b.nodes[node] = callee
+	if b.resolveLocal(node) {
+		return
+	}
+
+	if !callee.Defined() {
+		b.maskArguments(node, "")
+		b.resolveLookup(node, env)
+		b.resolveFieldCall(node)
+	}
+	b.resolveArguments(node, env, callee)
+	b.resolveS4Call(node, callee)
+}
+
+// resolveArguments resolves the values passed to a call,
+// named arguments are matched to the formals of the callee
+// when it is a function defined in the project.
+func (b *Binder) resolveArguments(node *ast.CallExpression, env *environment.Environment, callee *environment.Binding) {
+	b.matchArguments(node, callee)
+
+	for _, a := range node.Arguments {
+		if site, ok := b.maskedArgs[a]; ok {
+			// with(.state, token), token is a member of .state
+			maskedEnv := b.maskedEnv
+			b.maskedEnv = b.renameEnvs && (node.Name == "with" || node.Name == "within") && b.isEnv(node.Arguments[0].Value, env)
+			b.resolveMasked(a.Value, env, site)
+			b.maskedEnv = maskedEnv
+			continue
+		}
+
+		b.resolve(a.Value, env)
+	}
+}
+
+// matchArguments matches the named arguments of a call
+// to the formals of the callee, or to those its dots reach.
+func (b *Binder) matchArguments(node *ast.CallExpression, callee *environment.Binding) {
+	formals := b.formals(callee)
+
+	if formals == nil {
+		return
+	}
+
+	for _, a := range node.Arguments {
+		if a.Name == "" {
+			continue
+		}
+
+		if formal := formals.GetBinding(a.Name, false); formal != nil && formal.Kind == environment.Parameter {
+			b.arguments[a] = formal
+			continue
+		}
+
+		b.resolveDots(a, b.functions[callee])
+	}
+}
+
+// formals returns the scope of the function a binding refers to
+func (b *Binder) formals(callee *environment.Binding) *environment.Environment {
+	if !callee.Defined() || !callee.Function {
+		return nil
+	}
+
+	fn, ok := b.functions[callee]
+
+	if !ok {
+		return nil
+	}
+
+	return b.scopes[fn]
+}
+
+func (b *Binder) lookup(env *environment.Environment, name string) *environment.Binding {
+	if binding := b.attachedBinding(env.GetBinding(name, true), name); binding != nil {
+		return binding
+	}
+
+	return b.external(name)
+}
+
+func isAssign(operator string) bool {
+	return operator == "<-" || operator == "="
+}
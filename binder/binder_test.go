@@ -0,0 +1,1333 @@
+package binder
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/devOpifex/obfuscator/ast"
+	"github.com/devOpifex/obfuscator/environment"
+	"github.com/devOpifex/obfuscator/lexer"
+	"github.com/devOpifex/obfuscator/parser"
+)
+
+func bind(t *testing.T, code string) (*Binder, lexer.Files) {
+	t.Helper()
+
+	l := lexer.NewTest(code)
+	l.Run()
+
+	p := parser.New(l)
+	p.Run()
+
+	if p.HasError() {
+		t.Fatal(p.Errors())
+	}
+
+	b := New(environment.New(), p.Files())
+	b.Run()
+
+	return b, p.Files()
+}
+
+// identifiers returns the bindings of all identifiers with the given name
+func identifiers(b *Binder, name string) []*environment.Binding {
+	var bindings []*environment.Binding
+	for node, binding := range b.nodes {
+		if ident, ok := node.(*ast.Identifier); ok && ident.Value == name {
+			bindings = append(bindings, binding)
+		}
+	}
+	return bindings
+}
+
+func TestParameterAndGlobal(t *testing.T) {
+	code := `x <- 1
+foo <- function(x) {
+  x + 1
+}
+y <- x`
+
+	b, files := bind(t, code)
+
+	fn := files[0].Ast.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	param := b.Argument(fn.Parameters[0])
+
+	if param.Kind != environment.Parameter {
+		t.Fatalf("expected parameter, got %v", param.Kind)
+	}
+
+	global := b.env.GetBinding("x", false)
+
+	if global.Kind != environment.Global {
+		t.Fatalf("expected global, got %v", global.Kind)
+	}
+
+	if param.ID == global.ID {
+		t.Fatal("parameter and global x share a binding")
+	}
+
+	var params, globals int
+	for _, binding := range identifiers(b, "x") {
+		switch binding {
+		case param:
+			params++
+		case global:
+			globals++
+		}
+	}
+
+	if params != 1 || globals != 2 {
+		t.Fatalf("expected 1 parameter and 2 global x, got %v and %v", params, globals)
+	}
+}
+
+func TestOrderIndependent(t *testing.T) {
+	code := `foo <- function() {
+  helper <- function() {
+    total
+  }
+  for (i in 1:3) {
+    total <- i
+  }
+  helper()
+}
+bar()
+bar <- function() {
+  i
+}`
+
+	b, _ := bind(t, code)
+
+	for _, binding := range identifiers(b, "total") {
+		if binding.Kind != environment.Local {
+			t.Fatalf("expected total to be local, got %v", binding.Kind)
+		}
+	}
+
+	// i in bar is not the loop variable of foo
+	kinds := make(map[environment.Kind]int)
+	for _, binding := range identifiers(b, "i") {
+		kinds[binding.Kind]++
+	}
+
+	if kinds[environment.Local] != 1 || kinds[environment.External] != 1 {
+		t.Fatalf("expected one local and one external i, got %v", kinds)
+	}
+
+	for node, binding := range b.nodes {
+		if call, ok := node.(*ast.CallExpression); ok && call.Name == "bar" && binding.Kind != environment.Global {
+			t.Fatalf("bar called before definition should be global, got %v", binding.Kind)
+		}
+	}
+}
+
+func TestExternal(t *testing.T) {
+	code := `c <- 1
+x <- c(c, 2)
+y <- dplyr::filter(x, amount > 0)`
+
+	b, _ := bind(t, code)
+
+	for node, binding := range b.nodes {
+		call, ok := node.(*ast.CallExpression)
+
+		if !ok {
+			continue
+		}
+
+		// c is a variable, the call resolves to base::c
+		if binding.Kind != environment.External {
+			t.Fatalf("expected %v to be external, got %v", call.Name, binding.Kind)
+		}
+	}
+
+	for _, binding := range identifiers(b, "amount") {
+		if binding.Kind != environment.External {
+			t.Fatalf("expected amount to be external, got %v", binding.Kind)
+		}
+	}
+}
+
+func TestNamedArguments(t *testing.T) {
+	code := `res <- foo(data = 1, other = 2)
+foo <- function(data, ...) {
+  data
+}`
+
+	b, files := bind(t, code)
+
+	call := files[0].Ast.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.InfixExpression).Right.(*ast.CallExpression)
+	fn := files[0].Ast.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+
+	if b.Argument(call.Arguments[0]) != b.Argument(fn.Parameters[0]) {
+		t.Fatal("named argument not matched to formal")
+	}
+
+	if b.Argument(call.Arguments[1]) != nil {
+		t.Fatal("argument passed to dots matched a formal")
+	}
+}
+
+func TestSuperAssign(t *testing.T) {
+	code := `make_counter <- function() {
+  counter <- 0
+  function() {
+    counter <<- counter + 1
+    counter
+  }
+}
+
+reset <- function() {
+  total <<- 0
+}
+
+report <- function() {
+  total
+}
+
+shadow <- function(n) {
+  inner <- function() {
+    n <<- n + 1
+  }
+}`
+
+	b, _ := bind(t, code)
+
+	counters := identifiers(b, "counter")
+	if len(counters) != 4 {
+		t.Fatalf("expected 4 counter identifiers, got %v", len(counters))
+	}
+
+	for _, binding := range counters {
+		if binding != counters[0] || binding.Kind != environment.Local {
+			t.Fatalf("counter <<- did not resolve to the enclosing local: %v", binding.Kind)
+		}
+	}
+
+	// total is only ever assigned with <<-: it's a global
+	global := b.env.GetBinding("total", false)
+	if global == nil {
+		t.Fatal("total <<- did not create a global binding")
+	}
+
+	for _, binding := range identifiers(b, "total") {
+		if binding != global {
+			t.Fatalf("total resolved to %v instead of the global", binding.Kind)
+		}
+	}
+
+	for _, binding := range identifiers(b, "n") {
+		if binding.Kind != environment.Parameter {
+			t.Fatalf("n <<- did not resolve to the parameter: %v", binding.Kind)
+		}
+	}
+}
+
+func TestCatalogue(t *testing.T) {
+	code := `x <- paste0("a", "b")
+y <- dplyr::filter(x, amount > 0)
+z <- stats::filter(x, rep(1, 3))
+w <- unknown(x)`
+
+	b, _ := bind(t, code)
+
+	expected := map[string]string{
+		"paste0":  "base",
+		"rep":     "base",
+		"unknown": "",
+	}
+
+	filters := map[string]bool{}
+
+	for node, binding := range b.nodes {
+		call, ok := node.(*ast.CallExpression)
+
+		if !ok {
+			continue
+		}
+
+		if call.Name == "filter" {
+			filters[binding.Package] = true
+			continue
+		}
+
+		if binding.Package != expected[call.Name] {
+			t.Fatalf("expected %v in %v, got %v", call.Name, expected[call.Name], binding.Package)
+		}
+	}
+
+	if !filters["dplyr"] || !filters["stats"] {
+		t.Fatalf("expected dplyr::filter and stats::filter, got %v", filters)
+	}
+
+	if len(b.Warnings()) > 0 {
+		t.Fatalf("expected no warning, got %v", b.Warnings())
+	}
+}
+
+func TestShadow(t *testing.T) {
+	code := `filter <- function(x) {
+  x
+}
+
+foo <- function(x) {
+  t <- function(y) {
+    y
+  }
+  t(x)
+}
+
+bar <- function(m) {
+  t(m)
+}`
+
+	b, _ := bind(t, code)
+
+	if len(b.Warnings()) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", b.Warnings())
+	}
+
+	var local, base int
+	for node, binding := range b.nodes {
+		call, ok := node.(*ast.CallExpression)
+
+		if !ok || call.Name != "t" {
+			continue
+		}
+
+		if binding.Defined() {
+			local++
+			continue
+		}
+
+		if binding.Package == "base" {
+			base++
+		}
+	}
+
+	if local != 1 || base != 1 {
+		t.Fatalf("expected 1 local t and 1 base::t, got %v and %v", local, base)
+	}
+}
+
+func TestS3(t *testing.T) {
+	code := `area <- function(shape) {
+  UseMethod("area")
+}
+
+area.square <- function(shape) {
+  shape$side * shape$side
+}
+
+as.data.frame.square <- function(x, ...) {
+  data.frame(side = x$side)
+}
+
+print.square <- function(x, ...) {
+  NextMethod("print")
+}
+
+as.invoice <- function(x) {
+  UseMethod("as.invoice")
+}
+
+as.invoice.list <- function(x) {
+  x
+}
+
+to_json.square <- function(x) {
+  x
+}
+
+helper.square <- function(x) {
+  x
+}`
+
+	l := lexer.NewTest(code)
+	l.Run()
+
+	p := parser.New(l)
+	p.Run()
+
+	b := New(environment.New(), p.Files())
+	b.DeclareMethod("to_json", "square")
+	b.Run()
+
+	expected := map[string][2]string{
+		"area.square":          {"area", "square"},
+		"as.data.frame.square": {"as.data.frame", "square"},
+		"print.square":         {"print", "square"},
+		"as.invoice.list":      {"as.invoice", "list"},
+		"to_json.square":       {"to_json", "square"},
+	}
+
+	for name, split := range expected {
+		m := b.Method(b.env.GetBinding(name, false))
+
+		if m == nil {
+			t.Fatalf("expected %v to be a method", name)
+		}
+
+		if m.Generic.Name != split[0] || m.Class != split[1] {
+			t.Fatalf("expected %v to be %v for %v, got %v for %v", name, split[0], split[1], m.Generic.Name, m.Class)
+		}
+	}
+
+	for _, name := range []string{"area", "as.invoice", "helper.square"} {
+		if b.Method(b.env.GetBinding(name, false)) != nil {
+			t.Fatalf("%v is not a method", name)
+		}
+	}
+
+	project := map[string]bool{
+		"area":          true,
+		"as.invoice":    true,
+		"as.data.frame": false,
+		"print":         false,
+		"to_json":       false,
+	}
+
+	for name, p := range project {
+		if g := b.Generic(name); g == nil || g.Project != p {
+			t.Fatalf("expected generic %v project to be %v", name, p)
+		}
+	}
+
+	if len(b.Generic("area").Methods) != 1 {
+		t.Fatalf("expected 1 method of area, got %v", b.Generic("area").Methods)
+	}
+}
+
+func TestClasses(t *testing.T) {
+	code := `new_invoice <- function(amount) {
+  x <- list(amount = amount)
+  class(x) <- c("invoice", "list")
+  x
+}
+
+print.invoice <- function(x, ...) {
+  cat("invoice", x$amount)
+}
+
+fail <- function() {
+  stop(errorCondition("failed", class = "billing_error"))
+}
+
+res <- tryCatch(fail(), billing_error = function(e) {
+  inherits(e, "error")
+}, error = function(e) {
+  NULL
+})
+
+y <- inherits(new_invoice(1), "invoice")`
+
+	l := lexer.NewTest(code)
+	l.Run()
+
+	p := parser.New(l)
+	p.Run()
+
+	b := New(environment.New(), p.Files())
+	b.RenameClasses()
+	b.Run()
+
+	for _, name := range []string{"invoice", "billing_error"} {
+		if b.Class(name) == nil {
+			t.Fatalf("expected %v to be a class", name)
+		}
+	}
+
+	for _, name := range []string{"list", "error"} {
+		if b.Class(name) != nil {
+			t.Fatalf("%v is a built-in class", name)
+		}
+	}
+
+	strs := map[string]int{}
+	for str := range b.classStrings {
+		if c := b.StringClass(str); c != nil {
+			strs[c.Name]++
+		}
+	}
+
+	if strs["invoice"] != 2 || strs["billing_error"] != 1 {
+		t.Fatalf("expected 2 invoice and 1 billing_error strings, got %v", strs)
+	}
+
+	var handlers int
+	for arg := range b.classHandlers {
+		if b.HandlerClass(arg) != nil {
+			handlers++
+		}
+	}
+
+	if handlers != 1 {
+		t.Fatalf("expected 1 handler, got %v", handlers)
+	}
+
+	m := b.Method(b.env.GetBinding("print.invoice", false))
+
+	if m == nil || m.Mask() != "print."+environment.Mask("invoice") {
+		t.Fatalf("expected print.invoice to be renamed with its class, got %v", m)
+	}
+}
+
+func TestClassComparisons(t *testing.T) {
+	code := `x <- structure(list(), class = "invoice")
+a <- class(x)[1] == "invoice"
+b <- "invoice" %in% class(x)
+c <- identical(class(x)[[1]], "invoice")
+label <- switch(class(x)[1], invoice = "bill", "other")
+kind <- switch(x$kind, invoice = "bill")`
+
+	l := lexer.NewTest(code)
+	l.Run()
+
+	p := parser.New(l)
+	p.Run()
+
+	b := New(environment.New(), p.Files())
+	b.RenameClasses()
+	b.Run()
+
+	var strs int
+	for str := range b.classStrings {
+		if c := b.StringClass(str); c != nil && c.Name == "invoice" {
+			strs++
+		}
+	}
+
+	if strs != 4 {
+		t.Fatalf("expected 4 invoice strings, got %v", strs)
+	}
+
+	// the arm of switch(x$kind) is not a class
+	var arms int
+	for arg := range b.classHandlers {
+		if b.HandlerClass(arg) != nil {
+			arms++
+		}
+	}
+
+	if arms != 1 {
+		t.Fatalf("expected 1 switch arm, got %v", arms)
+	}
+}
+
+func TestR6(t *testing.T) {
+	code := `Store <- R6::R6Class("Store",
+  public = list(
+    items = NULL,
+    initialize = function(items) {
+      self$items <- items
+      private$cache <- list()
+    },
+    load = function() {
+      private$fetch()
+    }
+  ),
+  private = list(
+    cache = NULL,
+    fetch = function() {
+      self$items
+    }
+  ),
+  active = list(
+    size = function() {
+      length(self$items)
+    }
+  )
+)
+
+Store$set("public", "reset", function() {
+  self$items <- NULL
+})
+
+s <- Store$new(1)
+s$load()
+s$size
+x <- list(cache = 1)
+x$cache`
+
+	l := lexer.NewTest(code)
+	l.Run()
+
+	p := parser.New(l)
+	p.Run()
+
+	b := New(environment.New(), p.Files())
+	b.ProtectPublicMembers()
+	b.Run()
+
+	visibility := map[string]Visibility{
+		"items": Public,
+		"load":  Public,
+		"reset": Public,
+		"size":  Active,
+	}
+
+	for name, v := range visibility {
+		m := b.public[name]
+
+		if m == nil || m.Visibility != v {
+			t.Fatalf("expected public member %v", name)
+		}
+
+		if m.Mask() != name {
+			t.Fatalf("public member %v is protected", name)
+		}
+	}
+
+	if _, ok := b.public["initialize"]; ok {
+		t.Fatal("initialize is not renamed")
+	}
+
+	for _, name := range []string{"cache", "fetch"} {
+		m := b.private[name]
+
+		if m == nil || m.Mask() == name {
+			t.Fatalf("expected private member %v to be renamed", name)
+		}
+	}
+
+	accesses := map[string]int{}
+	for node, m := range b.accesses {
+		accesses[m.Name]++
+
+		// x$cache is not private$cache
+		if ident, ok := node.(*ast.Identifier); ok && ident.Value == "cache" && m.Visibility != Private {
+			t.Fatal("cache accessed as a public member")
+		}
+	}
+
+	expected := map[string]int{
+		"items": 4,
+		"cache": 1,
+		"fetch": 1,
+		"load":  1,
+		"size":  1,
+	}
+
+	for name, n := range expected {
+		if accesses[name] != n {
+			t.Fatalf("expected %v accesses to %v, got %v", n, name, accesses[name])
+		}
+	}
+}
+
+func TestS4(t *testing.T) {
+	code := `Person <- setClass("Person", slots = c(name = "character", age = "numeric"))
+
+setGeneric("greet", function(obj) {
+  standardGeneric("greet")
+})
+
+setMethod("greet", "Person", function(obj) {
+  paste("hi", obj@name)
+})
+
+setMethod("show", "Person", function(object) {
+  cat(object@name)
+})
+
+p <- Person(name = "bob", age = 3)
+q <- new("Person", name = "alice")
+slot(p, "age") <- 4
+greet(p)
+is(p, "Person")`
+
+	b, _ := bind(t, code)
+
+	if b.S4Class("Person") == nil {
+		t.Fatal("expected Person to be a class")
+	}
+
+	if g := b.env.GetFunctionBinding("greet"); !g.Defined() {
+		t.Fatal("expected greet to be defined by setGeneric")
+	}
+
+	strs := map[string]int{}
+	for _, s := range b.s4Strings {
+		strs[s.Name]++
+	}
+
+	expected := map[string]int{
+		"Person": 5,
+		"greet":  3,
+		"age":    1,
+	}
+
+	for name, n := range expected {
+		if strs[name] != n {
+			t.Fatalf("expected %v strings %v, got %v", n, name, strs)
+		}
+	}
+
+	if strs["show"] != 0 {
+		t.Fatal("show is a generic of the methods package")
+	}
+
+	args := map[string]int{}
+	for _, s := range b.slotArgs {
+		args[s.Name]++
+	}
+
+	if args["name"] != 3 || args["age"] != 2 {
+		t.Fatalf("expected 3 name and 2 age arguments, got %v", args)
+	}
+
+	if len(b.slotNodes) != 2 {
+		t.Fatalf("expected 2 @ accesses, got %v", len(b.slotNodes))
+	}
+
+	var protected, masked int
+	for arg, binding := range b.arguments {
+		if arg.Name == "object" && binding.Protected {
+			protected++
+		}
+		if arg.Name == "obj" && !binding.Protected {
+			masked++
+		}
+	}
+
+	if protected != 1 || masked != 2 {
+		t.Fatalf("expected formals of show to be protected, got %v and %v", protected, masked)
+	}
+}
+
+func TestRefClass(t *testing.T) {
+	code := `Account <- setRefClass("Account",
+  fields = list(balance = "numeric"),
+  methods = list(
+    deposit = function(x) {
+      balance <<- balance + x
+      log_op(x)
+    },
+    log_op = function(x) {
+      cat(x)
+    },
+    show = function() {
+      cat(balance)
+    }
+  )
+)
+
+Savings <- setRefClass("Savings", contains = "Account", fields = list(rate = "numeric"),
+  methods = list(
+    interest = function() {
+      balance * rate
+    }
+  )
+)
+
+a <- Account$new(balance = 1)
+a$deposit(2)
+a$balance`
+
+	b, _ := bind(t, code)
+
+	if b.env.GetBinding("balance", false) != nil {
+		t.Fatal("balance <<- assigned a global")
+	}
+
+	var balance *environment.Binding
+	for _, binding := range identifiers(b, "balance") {
+		if balance == nil {
+			balance = binding
+		}
+
+		if binding != balance || binding.Kind != environment.Local {
+			t.Fatalf("expected balance to resolve to the field, got %v", binding)
+		}
+	}
+
+	if balance == nil {
+		t.Fatal("balance not resolved")
+	}
+
+	for node, binding := range b.nodes {
+		call, ok := node.(*ast.CallExpression)
+		if ok && call.Name == "log_op" && !binding.Defined() {
+			t.Fatal("expected log_op to resolve to the method")
+		}
+	}
+
+	for _, name := range []string{"balance", "deposit", "log_op", "rate", "interest"} {
+		if _, ok := b.public[name]; !ok {
+			t.Fatalf("expected %v to be a member", name)
+		}
+	}
+
+	if _, ok := b.public["show"]; ok {
+		t.Fatal("show is not renamed")
+	}
+
+	var fields int
+	for arg, m := range b.memberArgs {
+		if arg.Name == "balance" && m.Name == "balance" {
+			fields++
+		}
+	}
+
+	if fields != 2 {
+		t.Fatalf("expected balance in fields and new(), got %v", fields)
+	}
+}
+
+func TestElements(t *testing.T) {
+	code := `df <- data.frame(amount = 1, label = "a")
+names(df) <- c("amount", "label")
+df$total <- df$amount
+x <- df[["label"]]
+y <- res$status`
+
+	l := lexer.NewTest(code)
+	l.Run()
+
+	p := parser.New(l)
+	p.Run()
+
+	b := New(environment.New(), p.Files())
+	b.RenameElements([]string{"label"})
+	b.Run()
+
+	for _, name := range []string{"amount", "total"} {
+		if _, ok := b.elements[name]; !ok {
+			t.Fatalf("expected element %v", name)
+		}
+	}
+
+	for _, name := range []string{"label", "status"} {
+		if _, ok := b.elements[name]; ok {
+			t.Fatalf("unexpected element %v", name)
+		}
+	}
+
+	accesses := 0
+	for node := range b.elementNodes {
+		if b.Element(node) != nil {
+			accesses++
+		}
+	}
+
+	if accesses != 2 {
+		t.Fatalf("expected 2 accesses with $, got %v", accesses)
+	}
+
+	strs := 0
+	for str := range b.elementStrings {
+		if b.ElementString(str) != nil {
+			strs++
+		}
+	}
+
+	if strs != 1 {
+		t.Fatalf("expected 1 string naming an element, got %v", strs)
+	}
+}
+
+func TestDataMasking(t *testing.T) {
+	code := `threshold <- 1
+amount <- 2
+dt <- data.table::data.table(amount = 1)
+dt[amount > 0, .(total = sum(amount)), by = group]
+x <- c(1, 2)[1]
+df |> dplyr::filter(amount > !!threshold, amount < .env$threshold)
+stats::filter(x, amount)`
+
+	l := lexer.NewTest(code)
+	l.Run()
+
+	p := parser.New(l)
+	p.Run()
+
+	b := New(environment.New(), p.Files())
+	b.Run()
+
+	if b.env.GetBinding("by", false) != nil {
+		t.Fatal("by is an argument of dt[], not a variable")
+	}
+
+	if b.env.GetBinding("x", false) == nil {
+		t.Fatal("expected x to be declared")
+	}
+
+	columns := map[string]int{}
+	for node := range b.columns {
+		ident := node.(*ast.Identifier)
+		columns[ident.Value]++
+
+		if b.Node(node).Defined() {
+			t.Fatalf("column %v is bound to the variable", ident.Value)
+		}
+	}
+
+	expected := map[string]int{
+		"amount": 4,
+		"group":  1,
+	}
+
+	for name, n := range expected {
+		if columns[name] != n {
+			t.Fatalf("expected %v columns %v, got %v", n, name, columns[name])
+		}
+	}
+
+	if columns["threshold"] != 0 {
+		t.Fatal("!!threshold and .env$threshold are variables")
+	}
+
+	// one warning per ambiguous column
+	if len(b.Warnings()) != 4 {
+		t.Fatalf("expected 4 warnings, got %v", len(b.Warnings()))
+	}
+
+	// !! does not escape the columns of a data.table
+	if !strings.Contains(b.Warnings()[0].Message, "use ..amount") {
+		t.Fatalf("expected ..amount in %v", b.Warnings()[0].Message)
+	}
+}
+
+func TestDataMaskingVariable(t *testing.T) {
+	code := `report <- function(df, threshold) {
+  limit <- threshold * 2
+  dplyr::filter(df, amount > limit)
+}`
+
+	b, _ := bind(t, code)
+
+	limits := identifiers(b, "limit")
+	if len(limits) != 2 {
+		t.Fatalf("expected 2 limit, got %v", len(limits))
+	}
+
+	var local *environment.Binding
+	for _, binding := range limits {
+		if binding.Defined() {
+			local = binding
+		}
+	}
+
+	if local == nil || local.Kind != environment.Local {
+		t.Fatal("expected limit to be declared as a local variable")
+	}
+
+	// the column falls back to the variable in R, both keep their name
+	if !local.Protected {
+		t.Fatal("expected limit to be protected")
+	}
+
+	if identifiers(b, "threshold")[0].Protected {
+		t.Fatal("threshold is not masked, it is renamed")
+	}
+
+	if len(b.Warnings()) != 1 {
+		t.Fatalf("expected 1 warning, got %v", len(b.Warnings()))
+	}
+}
+
+func TestLookup(t *testing.T) {
+	code := `helper <- function(x) {
+  x + 1
+}
+state <- 1
+assign("counter", 0)
+f <- function(name) {
+  get(name)
+}
+exists("state")
+do.call("helper", list(1))
+lapply(1:2, "helper")
+lapply(1:2, "state")
+Reduce("sum", 1:3)
+lapply(1:2, helper)
+Reduce(` + "`+`" + `, 1:3)
+Filter(Negate(is.null), list(1, NULL))
+vals <- mget(c("state", "counter"))
+rm(list = "state")
+rm(list = ls())
+do.call(paste0("help", "er"), list())`
+
+	l := lexer.NewTest(code)
+	l.Run()
+
+	p := parser.New(l)
+	p.Run()
+
+	b := New(environment.New(), p.Files())
+	b.Run()
+
+	if b.env.GetBinding("counter", false) == nil {
+		t.Fatal("expected assign to bind counter")
+	}
+
+	lookups := map[string]int{}
+	for _, binding := range b.lookups {
+		lookups[binding.Name]++
+	}
+
+	expected := map[string]int{
+		"helper":  2,
+		"state":   3,
+		"counter": 2,
+		"sum":     0,
+	}
+
+	for name, n := range expected {
+		if lookups[name] != n {
+			t.Fatalf("expected %v lookups of %v, got %v", n, name, lookups[name])
+		}
+	}
+
+	// get(name) and do.call(paste0()) are computed, functions passed as values are not
+	if len(b.Warnings()) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", len(b.Warnings()))
+	}
+}
+
+func TestExports(t *testing.T) {
+	code := `#' @export
+summarise_data <- function(data, n = 1) {
+  helper(data = data)
+}
+
+helper <- function(data) {
+  data
+}
+
+api <- function(input) {
+  input
+}
+
+get_x <- function(x) {
+  x
+}`
+
+	l := lexer.NewTest(code)
+	l.Run()
+
+	p := parser.New(l)
+	p.Run()
+
+	b := New(environment.New(), p.Files())
+	b.Export("api")
+	b.ExportPattern("^get_")
+	b.Run()
+
+	protected := map[string]bool{
+		"summarise_data": true,
+		"api":            true,
+		"get_x":          true,
+		"helper":         false,
+	}
+
+	for binding, fn := range b.functions {
+		for _, param := range fn.Parameters {
+			if b.Argument(param).Protected != protected[binding.Name] {
+				t.Fatalf("expected parameter %v of %v protected: %v", param.Name, binding.Name, protected[binding.Name])
+			}
+		}
+	}
+}
+
+func TestClosures(t *testing.T) {
+	code := `counter <- make_counter()
+counter()
+make_counter <- function() {
+  n <- 0
+  function() {
+    n <<- n + 1
+  }
+}
+make_adder <- function(n) {
+  adder <- function(x) {
+    x + n
+  }
+  return(adder)
+}
+handlers <- list(ok = function(x) {
+  x
+}, code = 200)
+f <- handlers$ok
+g <- handlers[["ok"]]
+code <- handlers$code
+adders <- lapply(1:3, make_adder)
+h <- adders[[1]]
+f(1)
+h(2)`
+
+	b, _ := bind(t, code)
+
+	for _, name := range []string{"counter", "f", "g", "h"} {
+		binding := b.env.GetBinding(name, false)
+
+		if binding == nil || !binding.Function {
+			t.Fatalf("expected %v to be a function", name)
+		}
+	}
+
+	if b.env.GetBinding("code", false).Function {
+		t.Fatal("code is not a function")
+	}
+
+	for node, binding := range b.nodes {
+		if call, ok := node.(*ast.CallExpression); ok && (call.Name == "counter" || call.Name == "f" || call.Name == "h") && binding.Kind != environment.Global {
+			t.Fatalf("expected call to %v to be bound, got %v", call.Name, binding.Kind)
+		}
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	code := `plot_it <- function(data, type = c("line", "bar")) {
+  type <- match.arg(type)
+  deparse(substitute(data))
+}
+describe <- function(x, verbose) {
+  helper <- function(y) {
+    y
+  }
+  call <- match.call()
+  helper(call)
+}
+other <- function(z) {
+  sys.call()
+}`
+
+	b, _ := bind(t, code)
+
+	protected := map[string]bool{
+		"data":    true,
+		"type":    true,
+		"x":       true,
+		"verbose": true,
+		"y":       false,
+		"z":       false,
+	}
+
+	for param, binding := range b.arguments {
+		if _, ok := protected[param.Name]; !ok {
+			continue
+		}
+
+		if binding.Protected != protected[param.Name] {
+			t.Fatalf("expected parameter %v protected: %v", param.Name, protected[param.Name])
+		}
+	}
+
+	if len(b.Warnings()) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", len(b.Warnings()))
+	}
+}
+
+func TestDots(t *testing.T) {
+	code := `inner <- function(alpha, beta = 2) {
+  alpha + beta
+}
+wrapper <- function(...) {
+  inner(...)
+}
+outer <- function(...) {
+  wrapper(...)
+}
+joiner <- function(x, ...) {
+  paste(x, ...)
+}
+mixed <- function(...) {
+  inner(...)
+  base::list(...)
+}
+wrap <- function(x, ...) {
+  n <- length(list(...))
+  inner(x, ...)
+}
+outer(alpha = 1)
+joiner("a", sep = "-")
+mixed(alpha = 1)
+wrap(1, beta = 5)`
+
+	b, _ := bind(t, code)
+
+	for node := range b.nodes {
+		call, ok := node.(*ast.CallExpression)
+
+		if !ok || len(call.Arguments) == 0 {
+			continue
+		}
+
+		a := call.Arguments[len(call.Arguments)-1]
+
+		switch call.Name {
+		case "outer", "mixed", "wrap":
+			if formal := b.Argument(a); formal == nil || formal.Name != a.Name {
+				t.Fatalf("expected %v of %v to be forwarded to inner", a.Name, call.Name)
+			}
+		case "joiner":
+			if !b.Passthrough(a) {
+				t.Fatalf("expected %v of %v to pass through", a.Name, call.Name)
+			}
+		}
+	}
+
+	// the dots of mixed and wrap also reach list()
+	var forwarded int
+	for _, w := range b.Warnings() {
+		if strings.Contains(w.Message, "forwarded by dots") {
+			forwarded++
+		}
+	}
+
+	if forwarded != 2 {
+		t.Fatalf("expected 2 warnings, got %v", b.Warnings())
+	}
+}
+
+func TestEnvironments(t *testing.T) {
+	code := `counter <- local({
+  n <- 0
+  function() {
+    n <<- n + 1
+  }
+})
+counter()
+.state <- new.env()
+.state$token <- "abc"
+assign("user", "bob", envir = .state)
+get("user", envir = .state)
+.state[["token"]]
+with(.state, token)
+other <- list(token = 1)
+other$token`
+
+	l := lexer.NewTest(code)
+	l.Run()
+
+	p := parser.New(l)
+	p.Run()
+
+	b := New(environment.New(), p.Files())
+	b.RenameEnvironments()
+	b.Run()
+
+	if b.env.GetBinding("n", false) != nil {
+		t.Fatal("n is local to local()")
+	}
+
+	if binding := b.env.GetBinding("counter", false); binding == nil || !binding.Function {
+		t.Fatal("expected counter to be a function")
+	}
+
+	if b.env.GetBinding("user", false) != nil {
+		t.Fatal("user is assigned in .state")
+	}
+
+	members := map[string]int{}
+	for _, m := range b.envNodes {
+		members[m.Name]++
+	}
+	for _, m := range b.envStrings {
+		members[m.Name]++
+	}
+
+	expected := map[string]int{
+		"token": 3,
+		"user":  2,
+	}
+
+	for name, n := range expected {
+		if members[name] != n {
+			t.Fatalf("expected %v accesses to %v, got %v", n, name, members[name])
+		}
+	}
+}
+
+func TestBox(t *testing.T) {
+	files := lexer.Files{
+		{
+			Path: "app/main.R",
+			Content: []byte(`box::use(
+  app/logic/utils[fmt, calc = compute, ...],
+  u = app/logic/utils,
+  ./view/page,
+  dplyr[filter],
+)
+filter(df, amount > 0)
+u$compute(1)`),
+			Ast: &ast.Program{},
+		},
+		{
+			Path: "app/logic/utils.R",
+			Content: []byte(`compute <- function(n) { n }
+filter <- function(x) { x }`),
+			Ast: &ast.Program{},
+		},
+		{
+			Path:    "app/view/page.R",
+			Content: []byte(`render <- function(x) { x }`),
+			Ast:     &ast.Program{},
+		},
+	}
+
+	l := lexer.New(files)
+	l.Run()
+
+	p := parser.New(l)
+	p.Run()
+
+	if p.HasError() {
+		t.Fatal(p.Errors())
+	}
+
+	b := New(environment.New(), p.Files())
+	b.Run()
+
+	var specs []*boxSpec
+	for _, s := range b.boxUses {
+		specs = s
+	}
+
+	if len(specs) != 4 {
+		t.Fatalf("expected 4 modules, got %v", len(specs))
+	}
+
+	expected := []struct {
+		file     int
+		attached []string
+	}{
+		// ... attaches every name but is not an item
+		{1, []string{"fmt", "calc"}},
+		{1, nil},
+		{2, nil},
+		{-1, []string{"filter"}},
+	}
+
+	for i, e := range expected {
+		if specs[i].file != e.file {
+			t.Fatalf("expected module %v in file %v, got %v", i, e.file, specs[i].file)
+		}
+
+		var names []string
+		for _, item := range specs[i].attached {
+			names = append(names, item.alias())
+		}
+
+		if strings.Join(names, ",") != strings.Join(e.attached, ",") {
+			t.Fatalf("expected %v attached to module %v, got %v", e.attached, i, names)
+		}
+	}
+
+	if calc := b.env.GetBinding("calc", false); calc == nil || !calc.Function {
+		t.Fatal("expected calc to be a function")
+	}
+
+	for _, binding := range identifiers(b, "compute") {
+		if binding != b.env.GetBinding("compute", false) {
+			t.Fatal("expected compute to refer to the function of utils.R")
+		}
+	}
+
+	for node, binding := range b.nodes {
+		if call, ok := node.(*ast.CallExpression); ok && call.Name == "filter" && binding.Defined() {
+			t.Fatal("expected filter to refer to dplyr")
+		}
+	}
+}
@@ -0,0 +1,415 @@
+package binder
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/devOpifex/obfuscator/ast"
+	"github.com/devOpifex/obfuscator/environment"
+)
+
+// boxSpec is a module or a package imported with box::use, e.g.:
+// u = app/logic/utils[fmt, calc = compute]
+type boxSpec struct {
+	// u = app/logic/utils
+	alias *ast.Argument
+	// identifiers, and keywords of relative paths: . and ..
+	path     []ast.Node
+	attached []*boxItem
+	brackets bool
+	// index of the file of the project, -1 for a package
+	// or a module outside of the project
+	file int
+}
+
+// boxItem is a name of the attach list: fmt or calc = compute
+type boxItem struct {
+	name *ast.Identifier
+	// alias of the first item is an identifier: mod[calc = compute,
+	// alias of the following items is a named argument
+	aliasIdent *ast.Identifier
+	aliasArg   *ast.Argument
+	// binding of the alias in a module of the project
+	binding *environment.Binding
+}
+
+func (i *boxItem) alias() string {
+	if i.aliasIdent != nil {
+		return i.aliasIdent.Value
+	}
+
+	if i.aliasArg != nil {
+		return i.aliasArg.Name
+	}
+
+	return i.name.Value
+}
+
+// ModulePath returns the name of a component of the path
+// of a module in the obfuscated code: app/logic/utils
+func (b *Binder) ModulePath(node ast.Node) (string, bool) {
+	name, ok := b.modulePaths[node]
+	return name, ok
+}
+
+// boxUse returns the call of box::use, nil if it is not one
+func boxUse(node *ast.InfixExpression) *ast.CallExpression {
+	if node.Operator != "::" {
+		return nil
+	}
+
+	ident, ok := node.Left.(*ast.Identifier)
+	call, isCall := node.Right.(*ast.CallExpression)
+
+	if !ok || !isCall || ident.Value != "box" || call.Name != "use" {
+		return nil
+	}
+
+	return call
+}
+
+// declareBox binds the modules and the names box::use attaches,
+// it returns false if the node is not a call to box::use
+func (b *Binder) declareBox(node *ast.InfixExpression, env *environment.Environment) bool {
+	call := boxUse(node)
+
+	if call == nil {
+		return false
+	}
+
+	specs := boxSpecs(call)
+	b.boxUses[call] = specs
+
+	for _, spec := range specs {
+		spec.file = b.module(spec)
+
+		if spec.file < 0 {
+			b.declarePackage(spec)
+			continue
+		}
+
+		// the module is bound unless names are attached
+		if spec.alias != nil {
+			binding := b.bind(env, spec.alias.Name, environment.Global, false)
+			b.arguments[spec.alias] = binding
+			b.modules[binding] = true
+		} else if name, ok := spec.path[len(spec.path)-1].(*ast.Identifier); ok && !spec.brackets {
+			binding := b.bind(env, name.Value, environment.Global, false)
+			b.modules[binding] = true
+		}
+
+		for _, item := range spec.attached {
+			if item.alias() == item.name.Value {
+				continue
+			}
+
+			item.binding = b.bind(env, item.alias(), environment.Global, false)
+
+			if item.aliasArg != nil {
+				b.arguments[item.aliasArg] = item.binding
+			}
+		}
+	}
+
+	return true
+}
+
+// registerBox marks the aliases of functions attached from modules
+// once the files of all modules are declared: box::use(mod[calc = compute])
+func (b *Binder) registerBox() {
+	for _, specs := range b.boxUses {
+		for _, spec := range specs {
+			for _, item := range spec.attached {
+				if item.binding == nil {
+					continue
+				}
+
+				if target := b.env.GetBinding(item.name.Value, false); target != nil && target.Function {
+					item.binding.Function = true
+				}
+			}
+		}
+	}
+}
+
+// declarePackage records the names attached from a package,
+// in the file they refer to the package: box::use(dplyr[filter])
+func (b *Binder) declarePackage(spec *boxSpec) {
+	if len(spec.path) != 1 {
+		return
+	}
+
+	pkg, ok := spec.path[0].(*ast.Identifier)
+
+	if !ok {
+		return
+	}
+
+	if b.attached[b.file] == nil {
+		b.attached[b.file] = make(map[string]*environment.Binding)
+	}
+
+	for _, item := range spec.attached {
+		b.attached[b.file][item.alias()] = b.namespaced(pkg.Value, item.name.Value)
+	}
+}
+
+// resolveBox resolves the paths and the names of box::use,
+// it returns false if the node is not a call to box::use
+func (b *Binder) resolveBox(node *ast.InfixExpression, env *environment.Environment) bool {
+	call := boxUse(node)
+
+	if call == nil {
+		return false
+	}
+
+	b.nodes[call] = b.namespaced("box", "use")
+
+	for _, spec := range b.boxUses[call] {
+		b.resolveModulePath(spec)
+
+		pkg := ""
+		if spec.file < 0 && len(spec.path) == 1 {
+			if ident, ok := spec.path[0].(*ast.Identifier); ok {
+				pkg = ident.Value
+			}
+		}
+
+		for _, item := range spec.attached {
+			if spec.file < 0 {
+				b.nodes[item.name] = b.namespaced(pkg, item.name.Value)
+				continue
+			}
+
+			b.nodes[item.name] = b.lookup(b.env, item.name.Value)
+
+			if item.aliasIdent != nil {
+				b.nodes[item.aliasIdent] = env.GetBinding(item.aliasIdent.Value, true)
+			}
+		}
+	}
+
+	return true
+}
+
+// resolveModulePath records the names of the components of the path
+// of a module: those of the project are renamed like its files.
+func (b *Binder) resolveModulePath(spec *boxSpec) {
+	var names []*ast.Identifier
+	for _, p := range spec.path {
+		if ident, ok := p.(*ast.Identifier); ok {
+			names = append(names, ident)
+		}
+	}
+
+	for _, n := range names {
+		b.modulePaths[n] = n.Value
+	}
+
+	if spec.file < 0 {
+		return
+	}
+
+	f := b.files[spec.file]
+	original := strings.Split(strings.TrimSuffix(filepath.ToSlash(f.Path), filepath.Ext(f.Path)), "/")
+	obfuscated := strings.Split(strings.TrimSuffix(filepath.ToSlash(f.Obfuscated), filepath.Ext(f.Obfuscated)), "/")
+
+	// app/logic/__init__.R is the module app/logic
+	if original[len(original)-1] == "__init__" {
+		original = original[:len(original)-1]
+		obfuscated = obfuscated[:len(obfuscated)-1]
+	}
+
+	// the path is aligned with the end of the file path,
+	// directories above the root of the project are left as is
+	for i := 1; i <= len(names) && i <= len(obfuscated) && i <= len(original); i++ {
+		n := names[len(names)-i]
+
+		if original[len(original)-i] != n.Value || obfuscated[len(obfuscated)-i] == "" {
+			break
+		}
+
+		b.modulePaths[n] = obfuscated[len(obfuscated)-i]
+	}
+
+	// the module is bound to the name of its file,
+	// a file that is not renamed keeps the name of the module
+	if last := names[len(names)-1]; spec.alias == nil && !spec.brackets && b.modulePaths[last] == last.Value {
+		if binding := b.env.GetBinding(last.Value, false); b.modules[binding] {
+			binding.Protected = true
+		}
+	}
+}
+
+// module returns the index of the file of the project
+// a path refers to, -1 if there is none, e.g.: a package.
+// Relative paths start from the directory of the file.
+func (b *Binder) module(spec *boxSpec) int {
+	var parts []string
+	for _, p := range spec.path {
+		switch p := p.(type) {
+		case *ast.Identifier:
+			parts = append(parts, p.Value)
+		case *ast.Keyword:
+			parts = append(parts, p.Value)
+		}
+	}
+
+	if len(parts) < 2 {
+		return -1
+	}
+
+	relative := parts[0] == "." || parts[0] == ".."
+	target := strings.Join(parts, "/")
+
+	if relative {
+		target = filepath.ToSlash(filepath.Join(filepath.Dir(b.files[b.file].Path), target))
+	}
+
+	for i, f := range b.files {
+		path := filepath.ToSlash(filepath.Clean(f.Path))
+
+		for _, candidate := range []string{target + ".R", target + ".r", target + "/__init__.R", target + "/__init__.r"} {
+			if path == candidate || (!relative && strings.HasSuffix(path, "/"+candidate)) {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// resolveModuleMember resolves the name on the right of $
+// of a module of the project: utils$fmt(x)
+func (b *Binder) resolveModuleMember(node *ast.InfixExpression, env *environment.Environment) {
+	ident, ok := node.Left.(*ast.Identifier)
+
+	if !ok || !b.modules[env.GetBinding(ident.Value, true)] {
+		return
+	}
+
+	var name string
+	switch right := node.Right.(type) {
+	case *ast.Identifier:
+		name = right.Value
+	case *ast.CallExpression:
+		name = right.Name
+	default:
+		return
+	}
+
+	if binding := b.env.GetBinding(name, false); binding.Defined() {
+		b.nodes[node.Right] = binding
+	}
+}
+
+// attachedBinding returns the symbol a file attaches from a package
+// in place of a global of the project: box::use(dplyr[filter]),
+// unless the file defines it.
+func (b *Binder) attachedBinding(binding *environment.Binding, name string) *environment.Binding {
+	if binding != nil && binding.Kind != environment.Global {
+		return binding
+	}
+
+	attached, ok := b.attached[b.file][name]
+
+	if !ok || b.defined[b.file][name] {
+		return binding
+	}
+
+	return attached
+}
+
+// boxSpecs splits the arguments of box::use into modules,
+// the parser splits the attach list at commas:
+// mod[a, b] is the arguments mod[a and b]
+func boxSpecs(call *ast.CallExpression) []*boxSpec {
+	var specs []*boxSpec
+	var open *boxSpec
+
+	for _, a := range call.Arguments {
+		if open != nil {
+			if open.parse(a.Value, a) {
+				open = nil
+			}
+			continue
+		}
+
+		spec := &boxSpec{}
+		if a.Name != "" {
+			spec.alias = a
+		}
+
+		if !spec.parse(a.Value, nil) && spec.brackets {
+			open = spec
+		}
+
+		specs = append(specs, spec)
+	}
+
+	return specs
+}
+
+// parse reads a node of a spec, arg is the named argument of an
+// item of the attach list: calc = compute]. It returns whether
+// the attach list is closed.
+func (s *boxSpec) parse(node ast.Node, arg *ast.Argument) bool {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		if !s.brackets {
+			s.path = append(s.path, node)
+			return false
+		}
+
+		item := &boxItem{name: node}
+		if arg != nil && arg.Name != "" {
+			item.aliasArg = arg
+		}
+		s.attached = append(s.attached, item)
+
+	// . and .. of relative paths, ... attaches every name
+	case *ast.Keyword:
+		if !s.brackets {
+			s.path = append(s.path, node)
+		}
+
+	case *ast.InfixExpression:
+		switch node.Operator {
+		case "/":
+			s.parse(node.Left, nil)
+			return s.parse(node.Right, nil)
+
+		case "[":
+			s.parse(node.Left, nil)
+			s.brackets = true
+			return s.parse(node.Right, arg)
+
+		// mod[calc = compute
+		case "=":
+			closed := s.parse(node.Left, nil)
+
+			if len(s.attached) == 0 {
+				return closed
+			}
+
+			item := s.attached[len(s.attached)-1]
+			right := node.Right
+			if postfix, ok := right.(*ast.PostfixExpression); ok {
+				right = postfix.Left
+				closed = true
+			}
+
+			if name, ok := right.(*ast.Identifier); ok {
+				item.aliasIdent, item.name = item.name, name
+			}
+
+			return closed
+		}
+
+	case *ast.PostfixExpression:
+		s.parse(node.Left, arg)
+		return node.Postfix == "]"
+	}
+
+	return false
+}
@@ -0,0 +1,217 @@
+package binder
+
+import (
+	"github.com/devOpifex/obfuscator/ast"
+	"github.com/devOpifex/obfuscator/catalogue"
+	"github.com/devOpifex/obfuscator/environment"
+)
+
+// Class is an S3 (or condition) class defined in the project,
+// it lives in strings: class(x) <- "invoice"
+type Class struct {
+	Name string
+}
+
+// Mask returns the name of the class in the obfuscated code.
+func (c *Class) Mask() string {
+	return environment.Mask(c.Name)
+}
+
+// functions whose class argument defines a class
+var classArguments = map[string]bool{
+	"structure":        true,
+	"errorCondition":   true,
+	"warningCondition": true,
+	"abort":            true,
+	"warn":             true,
+	"inform":           true,
+}
+
+// functions whose named arguments are handlers of condition classes,
+// but for the arguments listed
+var handlers = map[string]map[string]bool{
+	"tryCatch":              {"expr": true, "finally": true},
+	"withCallingHandlers":   {"expr": true},
+	"globalCallingHandlers": {},
+}
+
+// RenameClasses enables the renaming of the classes defined
+// in the project, it must be called before Run.
+func (b *Binder) RenameClasses() {
+	b.renameClasses = true
+}
+
+// StringClass returns the class a string refers to, if any.
+func (b *Binder) StringClass(str *ast.StringLiteral) *Class {
+	return b.classes[b.classStrings[str]]
+}
+
+// HandlerClass returns the class of a condition handler, e.g.:
+// tryCatch(expr, billing_error = function(e) {}), or of an arm
+// of switch(class(x)[1], invoice = "bill")
+func (b *Binder) HandlerClass(arg *ast.Argument) *Class {
+	return b.classes[b.classHandlers[arg]]
+}
+
+// Class returns a class of the project by name.
+func (b *Binder) Class(name string) *Class {
+	return b.classes[name]
+}
+
+// declareClasses records the strings referring to classes:
+// those that define a class and those that use it.
+func (b *Binder) declareClasses(node ast.Node) {
+	if !b.renameClasses {
+		return
+	}
+
+	switch node := node.(type) {
+	// class(x) <- "invoice", attr(x, "class") <- "invoice"
+	// class(x)[1] == "invoice", "invoice" %in% class(x)
+	case *ast.InfixExpression:
+		if isClass(node.Left) && isAssign(node.Operator) {
+			b.defineClasses(node.Right)
+			return
+		}
+
+		if node.Operator != "==" && node.Operator != "!=" && node.Operator != "%in%" {
+			return
+		}
+
+		if isClass(node.Left) {
+			b.useClasses(node.Right)
+		}
+
+		if isClass(node.Right) {
+			b.useClasses(node.Left)
+		}
+
+	case *ast.CallExpression:
+		// switch(class(x)[1], invoice = "bill", "other")
+		if node.Name == "switch" && len(node.Arguments) > 0 && isClass(node.Arguments[0].Value) {
+			for _, a := range node.Arguments[1:] {
+				if a.Name != "" {
+					b.classHandlers[a] = a.Name
+				}
+			}
+			return
+		}
+
+		// identical(class(x), "invoice")
+		if node.Name == "identical" && len(node.Arguments) == 2 {
+			if isClass(node.Arguments[0].Value) {
+				b.useClasses(node.Arguments[1].Value)
+			}
+
+			if isClass(node.Arguments[1].Value) {
+				b.useClasses(node.Arguments[0].Value)
+			}
+			return
+		}
+
+		if node.Name == "inherits" {
+			for i, a := range node.Arguments {
+				if a.Name == "what" || (a.Name == "" && i == 1) {
+					b.useClasses(a.Value)
+				}
+			}
+			return
+		}
+
+		if except, ok := handlers[node.Name]; ok {
+			for _, a := range node.Arguments {
+				if a.Name != "" && !except[a.Name] {
+					b.classHandlers[a] = a.Name
+				}
+			}
+			return
+		}
+
+		if classArguments[node.Name] {
+			for _, a := range node.Arguments {
+				if a.Name == "class" {
+					b.defineClasses(a.Value)
+				}
+			}
+		}
+	}
+}
+
+// isClass returns whether a node is the class of an object
+// or one of its elements: class(x), class(x)[1], class(x)[[1]]
+func isClass(node ast.Node) bool {
+	if infix, ok := node.(*ast.InfixExpression); ok && (infix.Operator == "[" || infix.Operator == "[[") {
+		node = infix.Left
+	}
+
+	call, ok := node.(*ast.CallExpression)
+
+	return ok && isClassCall(call)
+}
+
+// isClassCall returns whether a call gets the class of an object:
+// class(x), oldClass(x) or attr(x, "class")
+func isClassCall(call *ast.CallExpression) bool {
+	if call.Name == "class" || call.Name == "oldClass" {
+		return true
+	}
+
+	if call.Name != "attr" || len(call.Arguments) < 2 {
+		return false
+	}
+
+	str, ok := call.Arguments[1].Value.(*ast.StringLiteral)
+
+	return ok && str.Str == "class"
+}
+
+func (b *Binder) defineClasses(node ast.Node) {
+	for _, str := range classStrings(node) {
+		b.classStrings[str] = str.Str
+
+		if !catalogue.IsClass(str.Str) {
+			b.definedClasses[str.Str] = true
+		}
+	}
+}
+
+func (b *Binder) useClasses(node ast.Node) {
+	for _, str := range classStrings(node) {
+		b.classStrings[str] = str.Str
+	}
+}
+
+// classStrings returns the strings of "invoice" or c("invoice", "list")
+func classStrings(node ast.Node) []*ast.StringLiteral {
+	switch node := node.(type) {
+	case *ast.StringLiteral:
+		return []*ast.StringLiteral{node}
+
+	case *ast.CallExpression:
+		if node.Name != "c" {
+			return nil
+		}
+
+		var strs []*ast.StringLiteral
+		for _, a := range node.Arguments {
+			if str, ok := a.Value.(*ast.StringLiteral); ok {
+				strs = append(strs, str)
+			}
+		}
+		return strs
+	}
+
+	return nil
+}
+
+// registerClasses keeps the classes defined in the project
+// and renames the methods of these classes.
+func (b *Binder) registerClasses() {
+	for name := range b.definedClasses {
+		b.classes[name] = &Class{Name: name}
+	}
+
+	for _, m := range b.methods {
+		m.class = b.classes[m.Class]
+	}
+}
@@ -0,0 +1,312 @@
+package binder
+
+import (
+	"github.com/devOpifex/obfuscator/ast"
+	"github.com/devOpifex/obfuscator/environment"
+)
+
+// closure is the assignment of a value that may be a function,
+// e.g.: counter <- make_counter()
+type closure struct {
+	binding *environment.Binding
+	value   ast.Node
+	env     *environment.Environment
+}
+
+// declareClosure records an assignment whose value may be a function,
+// or a list of functions: handlers <- list(ok = function(x) {})
+func (b *Binder) declareClosure(binding *environment.Binding, value ast.Node, env *environment.Environment) {
+	if call, ok := value.(*ast.CallExpression); ok && call.Name == "list" {
+		b.lists[binding] = call
+	}
+
+	b.closures = append(b.closures, closure{binding: binding, value: value, env: env})
+}
+
+// registerClosures marks the bindings of functions returned by
+// factories, taken from lists or built with lapply and Map,
+// calls to these bindings are then resolved. Assignments
+// may depend on one another so we iterate until nothing changes.
+func (b *Binder) registerClosures() {
+	for changed := true; changed; {
+		changed = false
+
+		for _, c := range b.closures {
+			if !c.binding.Function && b.isFunction(c.value, c.env) {
+				c.binding.Function = true
+				changed = true
+			}
+
+			if !b.functionLists[c.binding] && b.isFunctionList(c.value, c.env) {
+				b.functionLists[c.binding] = true
+				changed = true
+			}
+		}
+	}
+}
+
+// registerData marks the bindings only assigned values that cannot
+// be functions, calls skip these: c <- c(1, 2); c(c, 3)
+func (b *Binder) registerData() {
+	unknown := make(map[*environment.Binding]bool)
+	for _, c := range b.closures {
+		if !isData(c.value) {
+			unknown[c.binding] = true
+		}
+	}
+
+	for _, c := range b.closures {
+		c.binding.Data = !unknown[c.binding] && !c.binding.Function
+	}
+}
+
+// constructors of values that are not functions
+var dataCalls = map[string]bool{
+	"c":          true,
+	"list":       true,
+	"vector":     true,
+	"numeric":    true,
+	"character":  true,
+	"logical":    true,
+	"integer":    true,
+	"double":     true,
+	"factor":     true,
+	"matrix":     true,
+	"array":      true,
+	"data.frame": true,
+	"seq":        true,
+	"seq_len":    true,
+	"seq_along":  true,
+	"rep":        true,
+	"paste":      true,
+	"paste0":     true,
+	"sprintf":    true,
+	"length":     true,
+	"nrow":       true,
+	"ncol":       true,
+	"sum":        true,
+	"new.env":    true,
+}
+
+// operators whose result is not a function
+var dataOperators = map[string]bool{
+	"+":    true,
+	"-":    true,
+	"*":    true,
+	"/":    true,
+	"^":    true,
+	":":    true,
+	"==":   true,
+	"!=":   true,
+	"<":    true,
+	">":    true,
+	"<=":   true,
+	">=":   true,
+	"&":    true,
+	"&&":   true,
+	"|":    true,
+	"||":   true,
+	"%%":   true,
+	"%/%":  true,
+	"%in%": true,
+}
+
+// isData returns whether a value cannot be a function, e.g.: 1, "a", c(1, 2)
+func isData(node ast.Node) bool {
+	switch node := node.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.Boolean, *ast.Null, *ast.Keyword:
+		return true
+
+	case *ast.PrefixExpression:
+		return isData(node.Right)
+
+	case *ast.InfixExpression:
+		return dataOperators[node.Operator]
+
+	case *ast.CallExpression:
+		return dataCalls[node.Name]
+	}
+
+	return false
+}
+
+// isFunction returns whether a value is a function
+func (b *Binder) isFunction(node ast.Node, env *environment.Environment) bool {
+	switch node := node.(type) {
+	case *ast.FunctionLiteral:
+		return true
+
+	case *ast.Identifier:
+		binding := env.GetBinding(node.Value, true)
+		return binding != nil && binding.Function
+
+	// counter <- make_counter()
+	case *ast.CallExpression:
+		// counter <- local({ function() {} })
+		if scope, ok := b.locals[node]; ok {
+			block, _ := localBlock(node)
+			statements := block.Expression.Statements
+
+			if len(statements) == 0 {
+				return false
+			}
+
+			last, ok := statements[len(statements)-1].(*ast.ExpressionStatement)
+			return ok && b.isFunction(last.Expression, scope)
+		}
+
+		fn, ok := b.functions[env.GetFunctionBinding(node.Name)]
+		return ok && b.isFactory(fn)
+
+	// f <- handlers$ok, f <- handlers[["ok"]]
+	case *ast.InfixExpression:
+		ident, ok := node.Left.(*ast.Identifier)
+
+		if !ok {
+			return false
+		}
+
+		binding := env.GetBinding(ident.Value, true)
+
+		if b.functionLists[binding] {
+			return node.Operator == "$" || node.Operator == "[["
+		}
+
+		list, ok := b.lists[binding]
+
+		if !ok {
+			return false
+		}
+
+		name := ""
+		switch node.Operator {
+		case "$":
+			if right, ok := node.Right.(*ast.Identifier); ok {
+				name = right.Value
+			}
+		case "[[":
+			if str := elementString(node); str != nil {
+				name = str.Str
+			}
+		}
+
+		for _, a := range list.Arguments {
+			if a.Name != "" && a.Name == name {
+				return b.isFunction(a.Value, env)
+			}
+		}
+	}
+
+	return false
+}
+
+// isFunctionList returns whether a value is a list of functions
+// built with lapply or Map, e.g.: lapply(1:3, make_adder)
+func (b *Binder) isFunctionList(node ast.Node, env *environment.Environment) bool {
+	call, ok := node.(*ast.CallExpression)
+
+	if !ok {
+		return false
+	}
+
+	switch call.Name {
+	case "lapply", "Map", "mapply":
+	default:
+		return false
+	}
+
+	fun := argument(call, dynamics[call.Name].Position, dynamics[call.Name].Name)
+
+	if fun == nil {
+		return false
+	}
+
+	switch value := fun.Value.(type) {
+	case *ast.FunctionLiteral:
+		return b.isFactory(value)
+
+	case *ast.Identifier:
+		fn, ok := b.functions[env.GetFunctionBinding(value.Value)]
+		return ok && b.isFactory(fn)
+	}
+
+	return false
+}
+
+// isFactory returns whether a function returns a function:
+// its last expression or one of its return() is a function.
+func (b *Binder) isFactory(fn *ast.FunctionLiteral) bool {
+	// a function returning itself is not a factory
+	if b.visiting[fn] {
+		return false
+	}
+
+	b.visiting[fn] = true
+	defer delete(b.visiting, fn)
+
+	scope := b.scopes[fn]
+	for _, value := range returned(fn) {
+		if b.isFunction(value, scope) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// returned returns the values a function may return
+func returned(fn *ast.FunctionLiteral) []ast.Node {
+	if fn.Body == nil || len(fn.Body.Statements) == 0 {
+		return nil
+	}
+
+	var values []ast.Node
+	for _, s := range fn.Body.Statements {
+		values = append(values, returns(s)...)
+	}
+
+	last := fn.Body.Statements[len(fn.Body.Statements)-1]
+	if s, ok := last.(*ast.ExpressionStatement); ok && s.Expression != nil {
+		values = append(values, s.Expression)
+	}
+
+	return values
+}
+
+// returns returns the values of return() in a node,
+// nested functions return from themselves.
+func returns(node ast.Node) []ast.Node {
+	switch node := node.(type) {
+	case *ast.ExpressionStatement:
+		if node.Expression != nil {
+			return returns(node.Expression)
+		}
+
+	case *ast.BlockStatement:
+		if node == nil {
+			return nil
+		}
+
+		var values []ast.Node
+		for _, s := range node.Statements {
+			values = append(values, returns(s)...)
+		}
+		return values
+
+	case *ast.IfExpression:
+		return append(returns(node.Consequence), returns(node.Alternative)...)
+
+	case *ast.For:
+		return returns(node.Value)
+
+	case *ast.While:
+		return returns(node.Value)
+
+	case *ast.CallExpression:
+		if node.Name == "return" && len(node.Arguments) == 1 {
+			return []ast.Node{node.Arguments[0].Value}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package binder
+
+import (
+	"fmt"
+
+	"github.com/devOpifex/obfuscator/ast"
+	"github.com/devOpifex/obfuscator/diagnostics"
+	"github.com/devOpifex/obfuscator/environment"
+)
+
+// Passthrough returns whether a named argument is collected by the
+// dots of the callee and does not reach a function of the project,
+// e.g.: the sep of wrapper(sep = "-") with wrapper <- function(...) paste(...)
+func (b *Binder) Passthrough(arg *ast.Argument) bool {
+	return b.passthrough[arg]
+}
+
+// dotsTargets are the functions the dots of a function are forwarded to
+type dotsTargets struct {
+	// formals of functions of the project matching the name
+	formals []*environment.Binding
+	// the dots reach a function of another package or are captured
+	external bool
+}
+
+// resolveDots matches a named argument the callee collects in its dots
+// with the formals of the functions of the project the dots are forwarded to,
+// the argument passes through when the dots reach none of these formals.
+func (b *Binder) resolveDots(arg *ast.Argument, fn *ast.FunctionLiteral) {
+	if !hasDots(fn) {
+		return
+	}
+
+	targets := &dotsTargets{}
+	b.forwarded(fn, arg.Name, targets, map[*ast.FunctionLiteral]bool{})
+
+	if len(targets.formals) == 0 {
+		b.passthrough[arg] = true
+		return
+	}
+
+	b.arguments[arg] = targets.formals[0]
+
+	conflict := targets.external
+	for _, formal := range targets.formals {
+		if formal.Protected != targets.formals[0].Protected {
+			conflict = true
+		}
+	}
+
+	if !conflict {
+		return
+	}
+
+	b.warnings = append(
+		b.warnings,
+		diagnostics.NewWarning(
+			arg.Token,
+			fmt.Sprintf("`%v` is forwarded by dots to functions that do not rename it alike, it is renamed as the formal of %v()", arg.Name, fn.Name),
+		),
+	)
+}
+
+// forwarded collects the formals a name passed in the dots of fn
+// is matched with, following the dots forwarded to other dots.
+func (b *Binder) forwarded(fn *ast.FunctionLiteral, name string, targets *dotsTargets, seen map[*ast.FunctionLiteral]bool) {
+	if seen[fn] {
+		return
+	}
+	seen[fn] = true
+
+	for _, call := range dotsCalls(fn.Body) {
+		if call == nil {
+			targets.external = true
+			continue
+		}
+
+		target, ok := b.functions[b.scopes[fn].GetFunctionBinding(call.Name)]
+
+		if !ok {
+			targets.external = true
+			continue
+		}
+
+		if binding := b.scopes[target].GetBinding(name, false); binding != nil && binding.Kind == environment.Parameter {
+			targets.formals = append(targets.formals, binding)
+			continue
+		}
+
+		if hasDots(target) {
+			b.forwarded(target, name, targets, seen)
+		}
+	}
+}
+
+// hasDots returns whether a function has a ... parameter
+func hasDots(fn *ast.FunctionLiteral) bool {
+	for _, p := range fn.Parameters {
+		if p.Name == "..." {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dotsCalls returns the calls that are passed the dots of a function,
+// calls of another namespace or through $ are nil.
+// Functions with their own dots are not walked.
+func dotsCalls(node ast.Node) []*ast.CallExpression {
+	var calls []*ast.CallExpression
+
+	switch node := node.(type) {
+	case *ast.ExpressionStatement:
+		if node.Expression != nil {
+			return dotsCalls(node.Expression)
+		}
+
+	case *ast.BlockStatement:
+		if node == nil {
+			return nil
+		}
+		for _, s := range node.Statements {
+			calls = append(calls, dotsCalls(s)...)
+		}
+
+	case ast.ExpressionBlock:
+		return dotsCalls(node.Expression)
+
+	case *ast.InfixExpression:
+		// pkg::fn(...), obj$fn(...)
+		if call, ok := node.Right.(*ast.CallExpression); ok && (node.Operator == "::" || node.Operator == ":::" || node.Operator == "$") {
+			calls = dotsCalls(call)
+			for i, c := range calls {
+				if c == call {
+					calls[i] = nil
+				}
+			}
+			return append(calls, dotsCalls(node.Left)...)
+		}
+
+		calls = append(dotsCalls(node.Left), dotsCalls(node.Right)...)
+
+	case *ast.PrefixExpression:
+		return dotsCalls(node.Right)
+
+	case *ast.PostfixExpression:
+		return dotsCalls(node.Left)
+
+	case *ast.FunctionLiteral:
+		if hasDots(node) {
+			return nil
+		}
+		return dotsCalls(node.Body)
+
+	case *ast.For:
+		calls = append(dotsCalls(node.Vector), dotsCalls(node.Value)...)
+
+	case *ast.While:
+		calls = append(dotsCalls(node.Statement), dotsCalls(node.Value)...)
+
+	case *ast.IfExpression:
+		calls = append(dotsCalls(node.Condition), dotsCalls(node.Consequence)...)
+		calls = append(calls, dotsCalls(node.Alternative)...)
+
+	case *ast.CallExpression:
+		for _, a := range node.Arguments {
+			if isDots(a.Value) {
+				calls = append(calls, node)
+				continue
+			}
+			calls = append(calls, dotsCalls(a.Value)...)
+		}
+	}
+
+	return calls
+}
+
+// isDots returns whether a node is ...
+func isDots(node ast.Node) bool {
+	k, ok := node.(*ast.Keyword)
+	return ok && k.Value == "..."
+}
@@ -0,0 +1,171 @@
+package binder
+
+import (
+	"github.com/devOpifex/obfuscator/ast"
+	"github.com/devOpifex/obfuscator/environment"
+)
+
+// Element is the name of a list element or a column
+// created in the project, e.g.: list(timeout = 30)
+type Element struct {
+	Name string
+}
+
+// Mask returns the name of the element in the obfuscated code.
+func (e *Element) Mask() string {
+	return environment.Mask(e.Name)
+}
+
+// calls whose named arguments create elements
+var elementConstructors = map[string]bool{
+	"list":       true,
+	"data.frame": true,
+	"tibble":     true,
+	"data.table": true,
+}
+
+// RenameElements enables the renaming of list elements and columns
+// created in the project, names in protect are left as is.
+// It must be called before Run.
+func (b *Binder) RenameElements(protect []string) {
+	b.renameElements = true
+
+	for _, p := range protect {
+		b.protectedElements[p] = true
+	}
+}
+
+// Element returns the element accessed with $, the node
+// is the right hand side: an identifier or a call.
+func (b *Binder) Element(node ast.Node) *Element {
+	return b.elements[b.elementNodes[node]]
+}
+
+// ElementArgument returns the element created by a named argument,
+// e.g.: list(timeout = 30)
+func (b *Binder) ElementArgument(arg *ast.Argument) *Element {
+	return b.elements[b.elementArgs[arg]]
+}
+
+// ElementString returns the element named by a string,
+// e.g.: x[["timeout"]], names(x) <- c("timeout")
+func (b *Binder) ElementString(str *ast.StringLiteral) *Element {
+	return b.elements[b.elementStrings[str]]
+}
+
+// declareElements records the elements created by node
+// and the sites where elements are accessed.
+func (b *Binder) declareElements(node ast.Node) {
+	if !b.renameElements {
+		return
+	}
+
+	switch node := node.(type) {
+	case *ast.CallExpression:
+		if !elementConstructors[node.Name] {
+			return
+		}
+
+		for _, a := range node.Arguments {
+			if a.Name != "" {
+				b.elementArgs[a] = a.Name
+				b.defineElement(a.Name)
+			}
+		}
+
+	case *ast.InfixExpression:
+		switch node.Operator {
+		case "$":
+			switch right := node.Right.(type) {
+			case *ast.Identifier:
+				b.elementNodes[right] = right.Value
+			case *ast.CallExpression:
+				b.elementNodes[right] = right.Name
+			}
+			return
+
+		case "[[":
+			if str := elementString(node); str != nil {
+				b.elementStrings[str] = str.Str
+			}
+			return
+
+		case "==", "!=", "%in%":
+			if isNames(node.Left) {
+				b.useElements(node.Right)
+			}
+			if isNames(node.Right) {
+				b.useElements(node.Left)
+			}
+			return
+		}
+
+		if !isAssign(node.Operator) {
+			return
+		}
+
+		// names(x) <- c("timeout", "retries")
+		if isNames(node.Left) {
+			for _, str := range classStrings(node.Right) {
+				b.elementStrings[str] = str.Str
+				b.defineElement(str.Str)
+			}
+			return
+		}
+
+		left, ok := node.Left.(*ast.InfixExpression)
+
+		if !ok {
+			return
+		}
+
+		// x$timeout <- 30
+		if ident, ok := left.Right.(*ast.Identifier); ok && left.Operator == "$" {
+			b.defineElement(ident.Value)
+		}
+
+		// x[["timeout"]] <- 30
+		if str := elementString(left); str != nil {
+			b.defineElement(str.Str)
+		}
+	}
+}
+
+func (b *Binder) defineElement(name string) {
+	if b.protectedElements[name] || environment.IsProtected(name) {
+		return
+	}
+
+	if _, ok := b.elements[name]; !ok {
+		b.elements[name] = &Element{Name: name}
+	}
+}
+
+func (b *Binder) useElements(node ast.Node) {
+	for _, str := range classStrings(node) {
+		b.elementStrings[str] = str.Str
+	}
+}
+
+// elementString returns the string of x[["name"]]
+func elementString(node *ast.InfixExpression) *ast.StringLiteral {
+	if node.Operator != "[[" {
+		return nil
+	}
+
+	postfix, ok := node.Right.(*ast.PostfixExpression)
+
+	if !ok {
+		return nil
+	}
+
+	str, _ := postfix.Left.(*ast.StringLiteral)
+
+	return str
+}
+
+// isNames returns whether a node is a call to names()
+func isNames(node ast.Node) bool {
+	call, ok := node.(*ast.CallExpression)
+	return ok && (call.Name == "names" || call.Name == "colnames")
+}
@@ -0,0 +1,141 @@
+package binder
+
+import (
+	"github.com/devOpifex/obfuscator/ast"
+	"github.com/devOpifex/obfuscator/environment"
+)
+
+// RenameEnvironments enables the renaming of the members of
+// environments created with new.env(), e.g.: .state$token
+// It must be called before Run.
+func (b *Binder) RenameEnvironments() {
+	b.renameEnvs = true
+}
+
+// EnvMember returns the member of an environment accessed with $,
+// the node is the right hand side: an identifier or a call.
+func (b *Binder) EnvMember(node ast.Node) *Element {
+	return b.envNodes[node]
+}
+
+// EnvString returns the member of an environment a string names,
+// e.g.: get("token", envir = .state)
+func (b *Binder) EnvString(str *ast.StringLiteral) *Element {
+	return b.envStrings[str]
+}
+
+// declareLocal declares the body of local({}) in its own scope
+func (b *Binder) declareLocal(node *ast.CallExpression, env *environment.Environment) bool {
+	block, ok := localBlock(node)
+
+	if !ok {
+		return false
+	}
+
+	scope := environment.Enclose(env)
+	b.locals[node] = scope
+	b.declare(block, scope, environment.Local)
+
+	return true
+}
+
+// resolveLocal resolves the body of local({}) in its scope
+func (b *Binder) resolveLocal(node *ast.CallExpression) bool {
+	scope, ok := b.locals[node]
+
+	if !ok {
+		return false
+	}
+
+	block, _ := localBlock(node)
+	b.resolve(block, scope)
+
+	return true
+}
+
+// localBlock returns the body of local({}), local() with
+// an envir argument evaluates in that environment.
+func localBlock(node *ast.CallExpression) (ast.ExpressionBlock, bool) {
+	if node.Name != "local" || len(node.Arguments) != 1 || node.Arguments[0].Name != "" {
+		return ast.ExpressionBlock{}, false
+	}
+
+	block, ok := node.Arguments[0].Value.(ast.ExpressionBlock)
+
+	return block, ok && block.Expression != nil
+}
+
+// declareEnv records .state <- new.env()
+func (b *Binder) declareEnv(binding *environment.Binding, value ast.Node) {
+	if ns, ok := value.(*ast.InfixExpression); ok && ns.Operator == "::" {
+		value = ns.Right
+	}
+
+	if call, ok := value.(*ast.CallExpression); ok && call.Name == "new.env" {
+		b.envs[binding] = true
+	}
+}
+
+// isEnv returns whether a node is an environment created with new.env()
+func (b *Binder) isEnv(node ast.Node, env *environment.Environment) bool {
+	ident, ok := node.(*ast.Identifier)
+	return ok && b.envs[env.GetBinding(ident.Value, true)]
+}
+
+func (b *Binder) envMember(name string) *Element {
+	if m, ok := b.envMembers[name]; ok {
+		return m
+	}
+
+	m := &Element{Name: name}
+	b.envMembers[name] = m
+
+	return m
+}
+
+// resolveEnvMember records .state$token and .state[["token"]]
+func (b *Binder) resolveEnvMember(node *ast.InfixExpression, env *environment.Environment) {
+	if !b.renameEnvs || !b.isEnv(node.Left, env) {
+		return
+	}
+
+	switch node.Operator {
+	case "$":
+		switch right := node.Right.(type) {
+		case *ast.Identifier:
+			b.envNodes[right] = b.envMember(right.Value)
+		case *ast.CallExpression:
+			b.envNodes[right] = b.envMember(right.Name)
+		}
+
+	case "[[":
+		if str := elementString(node); str != nil {
+			b.envStrings[str] = b.envMember(str.Str)
+		}
+	}
+}
+
+// resolveEnvLookup renames the string of get("token", envir = .state),
+// it returns false if envir is not an environment created with new.env().
+func (b *Binder) resolveEnvLookup(node *ast.CallExpression, env *environment.Environment) bool {
+	envir := argument(node, -1, "envir")
+
+	if envir == nil || !b.isEnv(envir.Value, env) {
+		return false
+	}
+
+	if !b.renameEnvs {
+		return true
+	}
+
+	d := dynamics[node.Name]
+
+	if a := argument(node, d.Position, d.Name); a != nil {
+		strs, _ := lookupStrings(a.Value)
+		for _, str := range strs {
+			b.envStrings[str] = b.envMember(str.Str)
+		}
+	}
+
+	return true
+}
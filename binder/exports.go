@@ -0,0 +1,106 @@
+package binder
+
+import (
+	"regexp"
+
+	"github.com/devOpifex/obfuscator/ast"
+	"github.com/devOpifex/obfuscator/environment"
+)
+
+// Export declares functions exported by the NAMESPACE, e.g.: export(fn),
+// their parameters keep their names. It must be called before Run.
+func (b *Binder) Export(names ...string) {
+	for _, name := range names {
+		b.exports[name] = true
+	}
+}
+
+// ExportPattern declares the functions exported by the NAMESPACE
+// with exportPattern("^[^\\.]"), an invalid pattern is ignored.
+// It must be called before Run.
+func (b *Binder) ExportPattern(pattern string) {
+	re, err := regexp.Compile(pattern)
+
+	if err != nil {
+		return
+	}
+
+	b.exportPatterns = append(b.exportPatterns, re)
+}
+
+// ProtectExports keeps the names of the exported functions,
+// e.g.: the API of a package. It must be called before Run.
+func (b *Binder) ProtectExports() {
+	b.keepExports = true
+}
+
+// isExported returns whether a function is part of the API of the project
+func (b *Binder) isExported(binding *environment.Binding, fn *ast.FunctionLiteral) bool {
+	if binding.Kind != environment.Global {
+		return false
+	}
+
+	return b.exportedFns[fn] || b.isExportedName(binding.Name) || environment.IsProtected(binding.Name)
+}
+
+// isExportedName returns whether the NAMESPACE exports a name
+func (b *Binder) isExportedName(name string) bool {
+	if b.exports[name] {
+		return true
+	}
+
+	for _, re := range b.exportPatterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// protectExports keeps the parameters of exported functions so
+// callers can still use named arguments: fn(data = df),
+// with ProtectExports the exported names are kept as well.
+func (b *Binder) protectExports() {
+	for binding, fn := range b.functions {
+		if !b.isExported(binding, fn) {
+			continue
+		}
+
+		b.protectParameters(fn)
+
+		if b.keepExports {
+			environment.Protect(binding.Name)
+		}
+	}
+
+	// methods of exported generics, or of generics of other packages,
+	// are passed the named arguments of the generic: format(x, digits = 4)
+	for binding, m := range b.methods {
+		if !m.Generic.Project || b.isExportedName(m.Generic.Name) {
+			b.protectParameters(b.functions[binding])
+		}
+	}
+
+	if !b.keepExports {
+		return
+	}
+
+	// exported objects other than functions: export(defaults)
+	for _, binding := range b.env.Bindings() {
+		if binding.Kind == environment.Global && b.isExportedName(binding.Name) {
+			environment.Protect(binding.Name)
+		}
+	}
+}
+
+// protectParameters keeps the names of the parameters of a function,
+// named arguments of its calls are then left as is.
+func (b *Binder) protectParameters(fn *ast.FunctionLiteral) {
+	for _, p := range fn.Parameters {
+		if formal := b.arguments[p]; formal != nil {
+			formal.Protected = true
+			environment.Reserve(formal.Name)
+		}
+	}
+}
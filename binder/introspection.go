@@ -0,0 +1,64 @@
+package binder
+
+import (
+	"fmt"
+
+	"github.com/devOpifex/obfuscator/ast"
+	"github.com/devOpifex/obfuscator/diagnostics"
+)
+
+// calls that read the formals of the function they are called in,
+// e.g.: match.arg(type) looks the choices up by the name of type
+var introspections = map[string]bool{
+	"match.arg":    true,
+	"match.call":   true,
+	"sys.function": true,
+	"formals":      true,
+	"formalArgs":   true,
+}
+
+// declareIntrospection records the functions whose parameters
+// must keep their names, and warns about the expressions
+// captured from the caller, these are obfuscated.
+func (b *Binder) declareIntrospection(node *ast.CallExpression) {
+	if b.fn == nil {
+		return
+	}
+
+	if introspections[node.Name] {
+		b.introspected[b.fn] = true
+		return
+	}
+
+	if node.Name == "sys.call" {
+		b.warnIntrospection(node, "sys.call()")
+		return
+	}
+
+	// deparse(substitute(x))
+	if node.Name != "deparse" || len(node.Arguments) == 0 {
+		return
+	}
+
+	if call, ok := node.Arguments[0].Value.(*ast.CallExpression); ok && call.Name == "substitute" {
+		b.warnIntrospection(node, "deparse(substitute())")
+	}
+}
+
+func (b *Binder) warnIntrospection(node *ast.CallExpression, call string) {
+	b.warnings = append(
+		b.warnings,
+		diagnostics.NewWarning(
+			node.Token,
+			fmt.Sprintf("%v captures the code of the caller, it returns the obfuscated code", call),
+		),
+	)
+}
+
+// protectIntrospected keeps the parameters of functions that
+// read their own formals, e.g.: match.call(), match.arg(type)
+func (b *Binder) protectIntrospected() {
+	for fn := range b.introspected {
+		b.protectParameters(fn)
+	}
+}
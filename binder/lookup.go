@@ -0,0 +1,246 @@
+package binder
+
+import (
+	"fmt"
+
+	"github.com/devOpifex/obfuscator/ast"
+	"github.com/devOpifex/obfuscator/diagnostics"
+	"github.com/devOpifex/obfuscator/environment"
+)
+
+// dynamic is the argument of a call that names a symbol
+// by string, e.g.: get("helper"), do.call("helper", args)
+type dynamic struct {
+	Position int
+	Name     string
+	// the argument is a function, it may be passed as is: lapply(x, helper)
+	Function bool
+}
+
+// calls that look symbols up by string
+var dynamics = map[string]dynamic{
+	"get":       {Position: 0, Name: "x"},
+	"get0":      {Position: 0, Name: "x"},
+	"exists":    {Position: 0, Name: "x"},
+	"assign":    {Position: 0, Name: "x"},
+	"mget":      {Position: 0, Name: "x"},
+	"rm":        {Position: -1, Name: "list"},
+	"remove":    {Position: -1, Name: "list"},
+	"match.fun": {Position: 0, Name: "FUN", Function: true},
+	"do.call":   {Position: 0, Name: "what", Function: true},
+	"lapply":    {Position: 1, Name: "FUN", Function: true},
+	"sapply":    {Position: 1, Name: "FUN", Function: true},
+	"vapply":    {Position: 1, Name: "FUN", Function: true},
+	"apply":     {Position: 2, Name: "FUN", Function: true},
+	"mapply":    {Position: 0, Name: "FUN", Function: true},
+	"Map":       {Position: 0, Name: "f", Function: true},
+	"Reduce":    {Position: 0, Name: "f", Function: true},
+	"Filter":    {Position: 0, Name: "f", Function: true},
+	"Find":      {Position: 0, Name: "f", Function: true},
+	"Position":  {Position: 0, Name: "f", Function: true},
+}
+
+// Lookup returns the binding a string of a dynamic lookup
+// refers to, e.g.: get("helper")
+func (b *Binder) Lookup(str *ast.StringLiteral) *environment.Binding {
+	return b.lookups[str]
+}
+
+// declareAssign binds the variable of assign("state", x) in the current
+// environment, or the global one with envir = .GlobalEnv or globalenv()
+func (b *Binder) declareAssign(node *ast.CallExpression, env *environment.Environment, kind environment.Kind) {
+	name := stringArgument(node, 0, "x")
+
+	if name == nil || !isName.MatchString(name.Str) {
+		return
+	}
+
+	for _, a := range node.Arguments {
+		if a.Name != "envir" {
+			continue
+		}
+
+		if !isGlobalEnv(a.Value) {
+			return
+		}
+
+		env, kind = b.env, environment.Global
+	}
+
+	b.bind(env, name.Str, kind, false)
+}
+
+// calls that build strings, the name of a function
+// passed to do.call() or lapply() is then computed
+var stringCalls = map[string]bool{
+	"paste":        true,
+	"paste0":       true,
+	"sprintf":      true,
+	"format":       true,
+	"gsub":         true,
+	"sub":          true,
+	"toupper":      true,
+	"tolower":      true,
+	"trimws":       true,
+	"substr":       true,
+	"substring":    true,
+	"as.character": true,
+	"deparse":      true,
+}
+
+// calls that return the names of existing symbols, e.g.: rm(list = ls())
+var namesCalls = map[string]bool{
+	"ls":      true,
+	"objects": true,
+}
+
+// resolveLookup resolves the strings of a dynamic lookup to the
+// symbols of the project they name, a computed name
+// cannot be resolved and raises a warning.
+func (b *Binder) resolveLookup(node *ast.CallExpression, env *environment.Environment) {
+	d, ok := dynamics[node.Name]
+
+	if !ok {
+		return
+	}
+
+	// get("token", envir = .state)
+	if !d.Function && b.resolveEnvLookup(node, env) {
+		return
+	}
+
+	arg := argument(node, d.Position, d.Name)
+
+	if arg == nil {
+		return
+	}
+
+	// get("token", envir = globalenv())
+	if envir := argument(node, -1, "envir"); envir != nil && isGlobalEnv(envir.Value) {
+		env = b.env
+	}
+
+	if d.Function {
+		b.resolveFunctionLookup(node, arg.Value, env)
+		return
+	}
+
+	strs, ok := lookupStrings(arg.Value)
+
+	for _, str := range strs {
+		if binding := env.GetBinding(str.Str, true); binding.Defined() {
+			b.lookups[str] = binding
+		}
+	}
+
+	if ok {
+		return
+	}
+
+	switch value := arg.Value.(type) {
+	case *ast.InfixExpression:
+		if value.Operator == "::" || value.Operator == "$" {
+			return
+		}
+
+	case *ast.CallExpression:
+		if namesCalls[value.Name] {
+			return
+		}
+	}
+
+	b.warnComputed(node)
+}
+
+// resolveFunctionLookup resolves the function passed to do.call(),
+// lapply(), etc. by name, functions passed as values, e.g.:
+// Reduce(`+`, x) or Filter(Negate(is.null), x), are not lookups.
+func (b *Binder) resolveFunctionLookup(node *ast.CallExpression, value ast.Node, env *environment.Environment) {
+	var binding *environment.Binding
+
+	switch value := value.(type) {
+	case *ast.StringLiteral:
+		binding = env.GetFunctionBinding(value.Str)
+
+		if binding.Defined() {
+			b.lookups[value] = binding
+		}
+
+	// fn <- "helper"; do.call(fn, args)
+	case *ast.Identifier:
+		if v := env.GetBinding(value.Value, true); v != nil && v.Data {
+			b.warnComputed(node)
+			return
+		}
+
+		binding = env.GetFunctionBinding(value.Value)
+
+	case *ast.CallExpression:
+		if stringCalls[value.Name] {
+			b.warnComputed(node)
+		}
+		return
+	}
+
+	// do.call("helper", list(x = 1)), the names of the list are arguments
+	if node.Name != "do.call" || !binding.Defined() {
+		return
+	}
+
+	if args := argument(node, 1, "args"); args != nil {
+		if list, ok := args.Value.(*ast.CallExpression); ok && list.Name == "list" {
+			b.matchArguments(list, binding)
+		}
+	}
+}
+
+func (b *Binder) warnComputed(node *ast.CallExpression) {
+	b.warnings = append(
+		b.warnings,
+		diagnostics.NewWarning(
+			node.Token,
+			fmt.Sprintf("the symbol %v() looks up is computed, it is not renamed", node.Name),
+		),
+	)
+}
+
+// lookupStrings returns the names of "helper" or c("a", "b"),
+// ok is false when a name is computed
+func lookupStrings(node ast.Node) ([]*ast.StringLiteral, bool) {
+	switch node := node.(type) {
+	case *ast.StringLiteral:
+		return []*ast.StringLiteral{node}, true
+
+	case *ast.CallExpression:
+		if node.Name != "c" {
+			return nil, false
+		}
+
+		var strs []*ast.StringLiteral
+		for _, a := range node.Arguments {
+			str, ok := a.Value.(*ast.StringLiteral)
+
+			if !ok {
+				return strs, false
+			}
+
+			strs = append(strs, str)
+		}
+
+		return strs, true
+	}
+
+	return nil, false
+}
+
+// isGlobalEnv returns whether a node is the global environment
+func isGlobalEnv(node ast.Node) bool {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		return node.Value == ".GlobalEnv"
+	case *ast.CallExpression:
+		return node.Name == "globalenv"
+	}
+
+	return false
+}
@@ -0,0 +1,303 @@
+package binder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/devOpifex/obfuscator/ast"
+	"github.com/devOpifex/obfuscator/diagnostics"
+	"github.com/devOpifex/obfuscator/environment"
+)
+
+// Masking describes a data-masking function: from the argument
+// at position From, bare symbols are columns of the data,
+// e.g.: dplyr::filter(df, amount > 0)
+type Masking struct {
+	From    int
+	Package string
+}
+
+// data-masking functions, From is 0 when there is no data argument
+var dataMasking = map[string]Masking{
+	"subset":    {From: 1, Package: "base"},
+	"transform": {From: 1, Package: "base"},
+	"with":      {From: 1, Package: "base"},
+	"within":    {From: 1, Package: "base"},
+
+	"filter":    {From: 1, Package: "dplyr"},
+	"mutate":    {From: 1, Package: "dplyr"},
+	"transmute": {From: 1, Package: "dplyr"},
+	"summarise": {From: 1, Package: "dplyr"},
+	"summarize": {From: 1, Package: "dplyr"},
+	"reframe":   {From: 1, Package: "dplyr"},
+	"arrange":   {From: 1, Package: "dplyr"},
+	"select":    {From: 1, Package: "dplyr"},
+	"rename":    {From: 1, Package: "dplyr"},
+	"relocate":  {From: 1, Package: "dplyr"},
+	"group_by":  {From: 1, Package: "dplyr"},
+	"count":     {From: 1, Package: "dplyr"},
+	"add_count": {From: 1, Package: "dplyr"},
+	"distinct":  {From: 1, Package: "dplyr"},
+	"pull":      {From: 1, Package: "dplyr"},
+	"slice_min": {From: 1, Package: "dplyr"},
+	"slice_max": {From: 1, Package: "dplyr"},
+	"aes":       {From: 0, Package: "ggplot2"},
+}
+
+// calls whose result is a data.table, x[] is then data-masking
+var dataTables = map[string]bool{
+	"data.table":    true,
+	"as.data.table": true,
+	"fread":         true,
+	"setDT":         true,
+}
+
+// DataMasking registers a data-masking function, bare symbols
+// from the argument at position from are left as is.
+// It must be called before Run.
+func (b *Binder) DataMasking(name string, from int) {
+	b.masking[name] = Masking{From: from}
+}
+
+// Column returns the element a bare symbol of a data-masking
+// call refers to, when elements are renamed, or the member of
+// the environment of with(), when environments are renamed.
+func (b *Binder) Column(node ast.Node) *Element {
+	if m, ok := b.envNodes[node]; ok {
+		return m
+	}
+
+	return b.elements[b.columns[node]]
+}
+
+// declareDataTable records x <- data.table() and setDT(x),
+// x[] then masks the columns of x.
+func (b *Binder) declareDataTable(node ast.Node, binding *environment.Binding, env *environment.Environment) {
+	switch node := node.(type) {
+	case *ast.CallExpression:
+		if node.Name == "setDT" && len(node.Arguments) > 0 {
+			if ident, ok := node.Arguments[0].Value.(*ast.Identifier); ok {
+				if binding := env.GetBinding(ident.Value, true); binding != nil {
+					b.dataTables[binding] = true
+				}
+			}
+			return
+		}
+
+		if binding != nil && dataTables[node.Name] {
+			b.dataTables[binding] = true
+		}
+
+	case *ast.InfixExpression:
+		if binding == nil {
+			return
+		}
+
+		// x <- data.table::fread()
+		if node.Operator == "::" {
+			b.declareDataTable(node.Right, binding, env)
+			return
+		}
+
+		// x <- dt[amount > 0]
+		if b.isDataTable(subsetted(node), env) {
+			b.dataTables[binding] = true
+		}
+	}
+}
+
+func (b *Binder) isDataTable(node ast.Node, env *environment.Environment) bool {
+	ident, ok := node.(*ast.Identifier)
+
+	if !ok {
+		return false
+	}
+
+	return b.dataTables[env.GetBinding(ident.Value, true)]
+}
+
+// subsetted returns x in x[...], the parser leaves the
+// content of brackets on the right: (x[amount) > 0
+func subsetted(node *ast.InfixExpression) ast.Node {
+	for {
+		if node.Operator == "[" {
+			return node.Left
+		}
+
+		left, ok := node.Left.(*ast.InfixExpression)
+
+		if !ok {
+			return nil
+		}
+
+		node = left
+	}
+}
+
+// maskArguments records the arguments of a data-masking call,
+// pkg is the namespace of the call, if any.
+func (b *Binder) maskArguments(node *ast.CallExpression, pkg string) {
+	m, ok := b.masking[node.Name]
+
+	if !ok {
+		return
+	}
+
+	if pkg != "" && m.Package != "" && pkg != m.Package {
+		return
+	}
+
+	from := m.From
+	if b.piped[node] && from > 0 {
+		from--
+	}
+
+	for i, a := range node.Arguments {
+		if i < from || a.Name == ".data" || a.Name == "data" {
+			continue
+		}
+
+		b.maskedArgs[a] = node.Name + "()"
+	}
+}
+
+// pipe records the call the data is piped to
+func (b *Binder) pipe(node ast.Node) {
+	if ns, ok := node.(*ast.InfixExpression); ok && ns.Operator == "::" {
+		node = ns.Right
+	}
+
+	if call, ok := node.(*ast.CallExpression); ok {
+		b.piped[call] = true
+	}
+}
+
+// resolveMasked resolves the bare symbols of node as columns
+func (b *Binder) resolveMasked(node ast.Node, env *environment.Environment, site string) {
+	masked := b.masked
+	b.masked = site
+	b.resolve(node, env)
+	b.masked = masked
+}
+
+// resolveColumn leaves a bare symbol of a data-masking call as is,
+// functions of the project are still resolved, e.g.: across(x, scale).
+// A variable of the same name keeps its name as R falls back to it
+// when the data has no such column, e.g.: filter(df, amount > limit)
+func (b *Binder) resolveColumn(node *ast.Identifier, env *environment.Environment) {
+	binding := env.GetBinding(node.Value, true)
+
+	if binding != nil && binding.Function {
+		b.nodes[node] = binding
+		return
+	}
+
+	b.nodes[node] = b.external(node.Value)
+	b.columns[node] = node.Value
+
+	if b.maskedEnv {
+		b.envNodes[node] = b.envMember(node.Value)
+		return
+	}
+
+	if !binding.Defined() {
+		return
+	}
+
+	binding.Protected = true
+	environment.Reserve(binding.Name)
+
+	escape := fmt.Sprintf("!!%v or .env$%v", node.Value, node.Value)
+	if strings.HasSuffix(b.masked, "[]") {
+		escape = ".." + node.Value
+	}
+
+	b.warnings = append(
+		b.warnings,
+		diagnostics.NewWarning(
+			node.Token,
+			fmt.Sprintf(
+				"`%v` is treated as a column in %v, the variable keeps its name, use %v for the variable",
+				node.Value, b.masked, escape,
+			),
+		),
+	)
+}
+
+// resolveDotDot resolves ..cols of a data.table as the variable cols,
+// ..1 and ... are left as is.
+func (b *Binder) resolveDotDot(node *ast.Keyword, env *environment.Environment) {
+	name := strings.TrimPrefix(node.Value, "..")
+
+	if name == node.Value || !isName.MatchString(name) || name[0] == '.' {
+		return
+	}
+
+	b.nodes[node] = b.lookup(env, name)
+}
+
+// resolveEscape resolves !!x and .env$x as variables
+// in a data-masking call.
+func (b *Binder) resolveEscape(node ast.Node, env *environment.Environment) {
+	masked := b.masked
+	b.masked = ""
+
+	switch node := node.(type) {
+	case *ast.PrefixExpression:
+		b.resolve(node, env)
+
+	case *ast.InfixExpression:
+		if ident, ok := node.Right.(*ast.Identifier); ok {
+			b.nodes[ident] = b.lookup(env, ident.Value)
+		}
+	}
+
+	b.masked = masked
+}
+
+// isEscape returns whether a node is !!x or .env$x
+func isEscape(node ast.Node) bool {
+	switch node := node.(type) {
+	case *ast.PrefixExpression:
+		right, ok := node.Right.(*ast.PrefixExpression)
+		return ok && node.Operator == "!" && right.Operator == "!"
+
+	case *ast.InfixExpression:
+		ident, ok := node.Left.(*ast.Identifier)
+		return ok && node.Operator == "$" && ident.Value == ".env"
+	}
+
+	return false
+}
+
+// bracket is an open x[ or x[[ with the data-masking
+// site that was active when it was opened
+type bracket struct {
+	operator string
+	masked   string
+}
+
+// openBracket and closeBracket track x[...]: the parser leaves
+// the content of brackets as a sequence of expressions,
+// brackets of a data.table mask its columns.
+func (b *Binder) openBracket(node *ast.InfixExpression, env *environment.Environment) {
+	b.brackets = append(b.brackets, bracket{operator: node.Operator, masked: b.masked})
+
+	if node.Operator == "[" && b.isDataTable(node.Left, env) {
+		b.masked = node.Left.(*ast.Identifier).Value + "[]"
+	}
+}
+
+// closeBracket closes a bracket, ]] may close two of them: x[y[1]]
+func (b *Binder) closeBracket(postfix string) {
+	n := 1
+	if postfix == "]]" && len(b.brackets) > 1 && b.brackets[len(b.brackets)-1].operator == "[" {
+		n = 2
+	}
+
+	for ; n > 0 && len(b.brackets) > 0; n-- {
+		last := b.brackets[len(b.brackets)-1]
+		b.brackets = b.brackets[:len(b.brackets)-1]
+		b.masked = last.masked
+	}
+}
@@ -0,0 +1,75 @@
+package binder
+
+import (
+	"github.com/devOpifex/obfuscator/environment"
+	"github.com/devOpifex/obfuscator/namespace"
+)
+
+// RenameNamespace renames the symbols of the project the directives
+// of a NAMESPACE refer to, consistently with the obfuscated code,
+// e.g.: S3method(summary, invoice, summary_invoice). It must be called after Run.
+func (b *Binder) RenameNamespace(ns *namespace.Namespace) {
+	for _, d := range ns.Directives {
+		switch d.Name {
+		case "S3method":
+			if g := b.Generic(d.Arg(0)); g != nil && g.Mask() != g.Name {
+				d.SetArg(0, g.Mask())
+			}
+
+			if c := b.Class(d.Arg(1)); c != nil {
+				d.SetArg(1, c.Mask())
+			}
+
+			// S3method(generic, class, function)
+			if len(d.Args) > 2 {
+				b.renameArg(d, 2)
+			}
+
+		case "export":
+			for i := range d.Args {
+				b.renameArg(d, i)
+			}
+
+		case "exportClasses":
+			for i := range d.Args {
+				if c := b.S4Class(d.Arg(i)); c != nil && c.Mask() != c.Name {
+					d.SetArg(i, c.Mask())
+				}
+			}
+
+		case "exportMethods":
+			for i := range d.Args {
+				if g, ok := b.s4Generics[d.Arg(i)]; ok && g.Mask() != g.Name {
+					d.SetArg(i, g.Mask())
+				}
+			}
+		}
+	}
+}
+
+// renameArg renames an argument naming an object of the project
+func (b *Binder) renameArg(d *namespace.Directive, i int) {
+	if name := b.Mask(d.Arg(i)); name != d.Arg(i) {
+		d.SetArg(i, name)
+	}
+}
+
+// Mask returns the name of a top level object in the obfuscated code,
+// names of other packages are left as is.
+func (b *Binder) Mask(name string) string {
+	binding := b.env.GetBinding(name, false)
+
+	if !binding.Defined() || binding.Protected {
+		return name
+	}
+
+	if m := b.Method(binding); m != nil {
+		return m.Mask()
+	}
+
+	if binding.Function && startWithDot(name) {
+		return name
+	}
+
+	return environment.Mask(name)
+}
@@ -0,0 +1,413 @@
+package binder
+
+import (
+	"fmt"
+
+	"github.com/devOpifex/obfuscator/ast"
+	"github.com/devOpifex/obfuscator/diagnostics"
+	"github.com/devOpifex/obfuscator/environment"
+)
+
+// Visibility of a member of an R6 class
+type Visibility int
+
+const (
+	Public Visibility = iota
+	Private
+	Active
+)
+
+// Member is a field or method of an R6 or reference class,
+// members are accessed through $: self$load(), private$cache
+type Member struct {
+	Name       string
+	Visibility Visibility
+	protected  bool
+	// variables of the member in the methods of reference classes
+	bindings []*environment.Binding
+}
+
+// methods R6 calls by name, these are never renamed
+var r6Reserved = map[string]bool{
+	"initialize": true,
+	"finalize":   true,
+	"print":      true,
+	"clone":      true,
+}
+
+// Mask returns the name of the member in the obfuscated code.
+func (m *Member) Mask() string {
+	if m.protected {
+		return m.Name
+	}
+
+	return environment.Mask(m.Name)
+}
+
+// ProtectPublicMembers keeps the names of the public
+// (and active) members of classes, private members are still renamed.
+// It must be called before Run.
+func (b *Binder) ProtectPublicMembers() {
+	b.protectMembers = true
+}
+
+// Member returns the member accessed with $, the node
+// is the right hand side: an identifier or a call.
+func (b *Binder) Member(node ast.Node) *Member {
+	return b.accesses[node]
+}
+
+// MemberArgument returns the member defined by an argument
+// of the lists passed to R6Class.
+func (b *Binder) MemberArgument(arg *ast.Argument) *Member {
+	return b.memberArgs[arg]
+}
+
+// MemberString returns the member named by a string, e.g.:
+// Store$set("public", "load", function() {})
+func (b *Binder) MemberString(str *ast.StringLiteral) *Member {
+	return b.memberStrings[str]
+}
+
+// declareR6 records the members of R6Class(public = list(), private = list()),
+// and those added with Class$set("public", "name", value).
+func (b *Binder) declareR6(node *ast.CallExpression) {
+	if node.Name == "set" {
+		b.declareR6Set(node)
+		return
+	}
+
+	for _, a := range node.Arguments {
+		visibility, ok := visibilities[a.Name]
+
+		if !ok {
+			continue
+		}
+
+		list, ok := a.Value.(*ast.CallExpression)
+
+		if !ok || list.Name != "list" {
+			continue
+		}
+
+		for _, m := range list.Arguments {
+			if m.Name == "" {
+				continue
+			}
+
+			if !r6Reserved[m.Name] {
+				b.memberArgs[m] = b.member(m.Name, visibility)
+			}
+		}
+	}
+}
+
+var visibilities = map[string]Visibility{
+	"public":  Public,
+	"private": Private,
+	"active":  Active,
+}
+
+func (b *Binder) declareR6Set(node *ast.CallExpression) {
+	if len(node.Arguments) < 2 {
+		return
+	}
+
+	which, ok := node.Arguments[0].Value.(*ast.StringLiteral)
+
+	if !ok {
+		return
+	}
+
+	visibility, ok := visibilities[which.Str]
+
+	if !ok {
+		return
+	}
+
+	name, ok := node.Arguments[1].Value.(*ast.StringLiteral)
+
+	if !ok {
+		return
+	}
+
+	if !r6Reserved[name.Str] {
+		b.memberStrings[name] = b.member(name.Str, visibility)
+	}
+}
+
+// member returns the member of the registry, private members
+// are kept apart as they can only be accessed through private$.
+func (b *Binder) member(name string, visibility Visibility) *Member {
+	members := b.public
+	if visibility == Private {
+		members = b.private
+	}
+
+	if m, ok := members[name]; ok {
+		return m
+	}
+
+	m := &Member{
+		Name:       name,
+		Visibility: visibility,
+		protected:  b.protectMembers && visibility != Private,
+	}
+	members[name] = m
+
+	return m
+}
+
+// r6Class is a generator: Store <- R6Class("Store", public = list())
+type r6Class struct {
+	call *ast.CallExpression
+	env  *environment.Environment
+}
+
+// objects through which members are accessed in methods
+var r6Self = map[string]bool{
+	"self":    true,
+	"private": true,
+	"super":   true,
+	".self":   true,
+}
+
+// declareR6Generator records Store <- R6Class("Store", ...),
+// objects created with Store$new() are instances of the class.
+func (b *Binder) declareR6Generator(node *ast.InfixExpression, env *environment.Environment) {
+	ident, ok := node.Left.(*ast.Identifier)
+
+	if !ok || !isAssign(node.Operator) || len(b.brackets) > 0 {
+		return
+	}
+
+	right := node.Right
+	if ns, ok := right.(*ast.InfixExpression); ok && ns.Operator == "::" {
+		right = ns.Right
+	}
+
+	call, ok := right.(*ast.CallExpression)
+
+	if !ok || call.Name != "R6Class" {
+		return
+	}
+
+	if binding := env.GetBinding(ident.Value, false); binding != nil {
+		b.r6Generators[binding] = &r6Class{call: call, env: env}
+	}
+}
+
+// registerInstances marks the bindings of objects created from
+// the generators of the project: s <- Store$new(), a <- Account(),
+// assignments may copy one another so we iterate until nothing changes.
+func (b *Binder) registerInstances() {
+	for changed := true; changed; {
+		changed = false
+
+		for _, c := range b.closures {
+			if !b.instances[c.binding] && b.isInstance(c.value, c.env) {
+				b.instances[c.binding] = true
+				changed = true
+			}
+		}
+	}
+}
+
+// isInstance returns whether a value is an object of a class of the project
+func (b *Binder) isInstance(node ast.Node, env *environment.Environment) bool {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		if r6Self[node.Value] {
+			return true
+		}
+
+		return b.instances[env.GetBinding(node.Value, true)]
+
+	// Account(balance = 0)
+	case *ast.CallExpression:
+		_, ok := b.refGenerators[env.GetBinding(node.Name, true)]
+		return ok
+
+	case *ast.InfixExpression:
+		if node.Operator != "$" {
+			return false
+		}
+
+		call, ok := node.Right.(*ast.CallExpression)
+
+		if !ok {
+			return false
+		}
+
+		// methods return the object to chain calls: s$add(1)$add(2)
+		if b.isInstance(node.Left, env) {
+			return true
+		}
+
+		ident, ok := node.Left.(*ast.Identifier)
+
+		if !ok || call.Name != "new" {
+			return false
+		}
+
+		return b.isGenerator(env.GetBinding(ident.Value, true))
+	}
+
+	return false
+}
+
+func (b *Binder) isGenerator(binding *environment.Binding) bool {
+	_, r6 := b.r6Generators[binding]
+	_, ref := b.refGenerators[binding]
+	return r6 || ref
+}
+
+// resolveMember records the member accessed with $ through self,
+// private or super in methods, or on an instance of a class:
+// private$x is a private member, anything else a public one.
+func (b *Binder) resolveMember(node *ast.InfixExpression, env *environment.Environment) {
+	var name string
+	switch right := node.Right.(type) {
+	case *ast.Identifier:
+		name = right.Value
+	case *ast.CallExpression:
+		name = right.Name
+	default:
+		return
+	}
+
+	if !b.isInstance(node.Left, env) {
+		b.protectMember(node, name, env)
+		return
+	}
+
+	ident, _ := node.Left.(*ast.Identifier)
+
+	if ident != nil && ident.Value == "private" {
+		if m, ok := b.private[name]; ok {
+			b.accesses[node.Right] = m
+		}
+		return
+	}
+
+	if m, ok := b.public[name]; ok {
+		b.accesses[node.Right] = m
+		return
+	}
+
+	// super$ reaches the private methods of the parent
+	if m, ok := b.private[name]; ok && ident != nil && ident.Value == "super" {
+		b.accesses[node.Right] = m
+	}
+}
+
+// protectMember keeps the name of a public member accessed on an
+// object whose class we cannot tell, e.g.: a parameter store$load(),
+// the access could not be renamed along with the member.
+func (b *Binder) protectMember(node *ast.InfixExpression, name string, env *environment.Environment) {
+	m, ok := b.public[name]
+
+	if !ok || m.protected || b.isNotInstance(node.Left, env) {
+		return
+	}
+
+	m.protected = true
+	for _, binding := range m.bindings {
+		binding.Protected = true
+	}
+	environment.Reserve(name)
+
+	b.warnings = append(
+		b.warnings,
+		diagnostics.NewWarning(
+			node.Token,
+			fmt.Sprintf("`%v` is accessed on an object of unknown class, the member %v keeps its name", name, name),
+		),
+	)
+}
+
+// isNotInstance returns whether an object is known not to be an
+// instance of a class: data, e.g.: config <- list(), a generator,
+// an environment or a box module
+func (b *Binder) isNotInstance(node ast.Node, env *environment.Environment) bool {
+	ident, ok := node.(*ast.Identifier)
+
+	if !ok {
+		return false
+	}
+
+	binding := env.GetBinding(ident.Value, true)
+
+	if binding == nil {
+		return false
+	}
+
+	return binding.Data || b.isGenerator(binding) || b.envs[binding] || b.modules[binding]
+}
+
+// resolveR6New matches the named arguments of Store$new(items = x)
+// to the formals of initialize, inherited from the parent class if need be.
+func (b *Binder) resolveR6New(node *ast.InfixExpression, env *environment.Environment) {
+	ident, ok := node.Left.(*ast.Identifier)
+
+	if !ok {
+		return
+	}
+
+	call, ok := node.Right.(*ast.CallExpression)
+
+	if !ok || call.Name != "new" {
+		return
+	}
+
+	init := b.initialize(b.r6Generators[env.GetBinding(ident.Value, true)], map[*r6Class]bool{})
+
+	if init == nil {
+		return
+	}
+
+	for _, a := range call.Arguments {
+		if a.Name == "" {
+			continue
+		}
+
+		if formal := b.scopes[init].GetBinding(a.Name, false); formal != nil && formal.Kind == environment.Parameter {
+			b.arguments[a] = formal
+			continue
+		}
+
+		b.resolveDots(a, init)
+	}
+}
+
+// initialize returns the initialize method of a class,
+// or that of its parent: R6Class(inherit = Store)
+func (b *Binder) initialize(class *r6Class, seen map[*r6Class]bool) *ast.FunctionLiteral {
+	if class == nil || seen[class] {
+		return nil
+	}
+	seen[class] = true
+
+	if public := argument(class.call, -1, "public"); public != nil {
+		if list, ok := public.Value.(*ast.CallExpression); ok && list.Name == "list" {
+			if init := argument(list, -1, "initialize"); init != nil {
+				fn, _ := init.Value.(*ast.FunctionLiteral)
+				return fn
+			}
+		}
+	}
+
+	parent := argument(class.call, -1, "inherit")
+
+	if parent == nil {
+		return nil
+	}
+
+	ident, ok := parent.Value.(*ast.Identifier)
+
+	if !ok {
+		return nil
+	}
+
+	return b.initialize(b.r6Generators[class.env.GetBinding(ident.Value, true)], seen)
+}
@@ -0,0 +1,242 @@
+package binder
+
+import (
+	"github.com/devOpifex/obfuscator/ast"
+	"github.com/devOpifex/obfuscator/environment"
+)
+
+// methods of reference classes called by name, these are never renamed
+var refReserved = map[string]bool{
+	"initialize":   true,
+	"finalize":     true,
+	"show":         true,
+	"copy":         true,
+	"callSuper":    true,
+	"initFields":   true,
+	"field":        true,
+	"export":       true,
+	"getClass":     true,
+	"getRefClass":  true,
+	"import":       true,
+	"methods":      true,
+	"fields":       true,
+	"trace":        true,
+	"untrace":      true,
+	"usingMethods": true,
+}
+
+// declareRefClass declares the fields and methods of
+// setRefClass("Account", fields = list(), methods = list()):
+// methods are enclosed in the object, where fields and other
+// methods are visible as variables, e.g.: balance <<- balance + x
+func (b *Binder) declareRefClass(node *ast.CallExpression, env *environment.Environment) *environment.Environment {
+	if scope, ok := b.refClasses[node]; ok {
+		return scope
+	}
+
+	scope := environment.Enclose(env)
+	b.refClasses[node] = scope
+
+	if class := stringArgument(node, 0, "Class"); class != nil {
+		b.refNames[class.Str] = scope
+	}
+
+	for _, a := range node.Arguments {
+		switch a.Name {
+		case "fields":
+			b.declareFields(a.Value, scope)
+		case "methods":
+			b.declareRefMethods(a.Value, scope)
+		}
+	}
+
+	return scope
+}
+
+// declareFields declares fields = list(balance = "numeric")
+// or fields = c("balance", "owner")
+func (b *Binder) declareFields(node ast.Node, scope *environment.Environment) {
+	call, ok := node.(*ast.CallExpression)
+
+	if !ok {
+		return
+	}
+
+	for _, a := range call.Arguments {
+		if a.Name != "" {
+			if m := b.declareRefMember(a.Name, scope, false); m != nil {
+				b.memberArgs[a] = m
+			}
+			continue
+		}
+
+		if str, ok := a.Value.(*ast.StringLiteral); ok {
+			if m := b.declareRefMember(str.Str, scope, false); m != nil {
+				b.memberStrings[str] = m
+			}
+		}
+	}
+}
+
+// declareRefMethods declares methods = list(deposit = function(x) {})
+// the functions are enclosed in the scope of the object
+func (b *Binder) declareRefMethods(node ast.Node, scope *environment.Environment) {
+	call, ok := node.(*ast.CallExpression)
+
+	if !ok {
+		return
+	}
+
+	args := call.Arguments
+
+	// Account$methods(list(deposit = function(x) {}))
+	if len(args) == 1 && args[0].Name == "" {
+		if list, ok := args[0].Value.(*ast.CallExpression); ok && list.Name == "list" {
+			args = list.Arguments
+		}
+	}
+
+	for _, a := range args {
+		if a.Name == "" {
+			continue
+		}
+
+		if m := b.declareRefMember(a.Name, scope, true); m != nil {
+			b.memberArgs[a] = m
+		}
+	}
+
+	for _, a := range args {
+		if fn, ok := a.Value.(*ast.FunctionLiteral); ok {
+			b.declare(fn, scope, environment.Local)
+		}
+	}
+}
+
+// declareRefMember binds a field or a method in the object
+// and registers it as a member accessed with $, e.g.: .self$balance
+func (b *Binder) declareRefMember(name string, scope *environment.Environment, fn bool) *Member {
+	binding := b.bind(scope, name, environment.Local, fn)
+
+	if refReserved[name] {
+		binding.Protected = true
+		return nil
+	}
+
+	m := b.member(name, Public)
+	m.bindings = append(m.bindings, binding)
+	binding.Protected = m.protected
+
+	return m
+}
+
+// declareRefMethodsCall declares the methods added with
+// Account$methods(deposit = function(x) {})
+func (b *Binder) declareRefMethodsCall(node *ast.InfixExpression, env *environment.Environment) {
+	ident, ok := node.Left.(*ast.Identifier)
+
+	if !ok {
+		return
+	}
+
+	call, ok := node.Right.(*ast.CallExpression)
+
+	if !ok || call.Name != "methods" {
+		return
+	}
+
+	scope, ok := b.refGenerators[env.GetBinding(ident.Value, true)]
+
+	if !ok {
+		return
+	}
+
+	b.declareRefMethods(call, scope)
+}
+
+// resolveRefNew renames the fields passed to Account$new(balance = 0)
+func (b *Binder) resolveRefNew(node *ast.InfixExpression, env *environment.Environment) {
+	ident, ok := node.Left.(*ast.Identifier)
+
+	if !ok {
+		return
+	}
+
+	call, ok := node.Right.(*ast.CallExpression)
+
+	if !ok || call.Name != "new" {
+		return
+	}
+
+	if _, ok := b.refGenerators[env.GetBinding(ident.Value, true)]; !ok {
+		return
+	}
+
+	b.fieldArguments(call)
+}
+
+// registerRefClasses makes the fields and methods of parent classes
+// visible in the methods of their children: contains = "Account"
+func (b *Binder) registerRefClasses() {
+	for node, scope := range b.refClasses {
+		b.inherit(node, scope, map[*ast.CallExpression]bool{})
+	}
+}
+
+func (b *Binder) inherit(node *ast.CallExpression, scope *environment.Environment, seen map[*ast.CallExpression]bool) {
+	if seen[node] {
+		return
+	}
+	seen[node] = true
+
+	parent := stringArgument(node, -1, "contains")
+	if parent == nil {
+		return
+	}
+
+	parentScope, ok := b.refNames[parent.Str]
+	if !ok {
+		return
+	}
+
+	for call, s := range b.refClasses {
+		if s == parentScope {
+			b.inherit(call, s, seen)
+		}
+	}
+
+	for _, binding := range parentScope.Bindings() {
+		if scope.GetBinding(binding.Name, false) == nil {
+			scope.SetBinding(binding)
+		}
+	}
+}
+
+// fieldArguments renames the named arguments that are fields
+func (b *Binder) fieldArguments(call *ast.CallExpression) {
+	for _, a := range call.Arguments {
+		if m, ok := b.public[a.Name]; ok {
+			b.memberArgs[a] = m
+		}
+	}
+}
+
+// resolveFieldCall renames the fields named by initFields(balance = 0)
+// and field("balance") in the methods of reference classes
+func (b *Binder) resolveFieldCall(node *ast.CallExpression) {
+	switch node.Name {
+	case "initFields":
+		b.fieldArguments(node)
+
+	case "field":
+		str := stringArgument(node, 0, "name")
+
+		if str == nil {
+			return
+		}
+
+		if m, ok := b.public[str.Str]; ok {
+			b.memberStrings[str] = m
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package binder
+
+import (
+	"strings"
+
+	"github.com/devOpifex/obfuscator/ast"
+)
+
+// declareRoxygen records the functions following a roxygen @export tag
+// and the methods registered with @exportS3Method or @S3method
+func (b *Binder) declareRoxygen(statements []ast.Statement) {
+	exported := false
+	var methods []*ast.RoxygenStatement
+
+	for _, s := range statements {
+		switch s := s.(type) {
+		case *ast.ExportStatement:
+			exported = true
+
+		case *ast.RoxygenStatement:
+			if s.Tag == "S3method" || s.Tag == "exportS3Method" {
+				methods = append(methods, s)
+			}
+
+		case *ast.CommentStatement:
+			continue
+
+		case *ast.ExpressionStatement:
+			if fn, ok := s.Expression.(*ast.FunctionLiteral); ok {
+				if exported {
+					b.exportedFns[fn] = true
+				}
+
+				for _, m := range methods {
+					b.declareMethodTag(m, fn.Name)
+				}
+			}
+			exported = false
+			methods = nil
+
+		default:
+			exported = false
+			methods = nil
+		}
+	}
+}
+
+// declareMethodTag declares the method a tag registers, e.g.:
+// @exportS3Method pkg::generic or @exportS3Method generic class,
+// a method named otherwise is registered by its name.
+func (b *Binder) declareMethodTag(tag *ast.RoxygenStatement, name string) {
+	words := strings.Fields(strings.Join(tag.Lines, " "))
+
+	if len(words) == 0 {
+		return
+	}
+
+	// pkg::generic
+	generic := words[0]
+	if i := strings.LastIndex(generic, "::"); i >= 0 {
+		generic = generic[i+2:]
+	}
+
+	if !strings.HasPrefix(name, generic+".") {
+		return
+	}
+
+	class := strings.TrimPrefix(name, generic+".")
+
+	if len(words) > 1 && words[1] != class {
+		return
+	}
+
+	b.DeclareMethod(generic, class)
+}
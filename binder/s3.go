@@ -0,0 +1,218 @@
+package binder
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/devOpifex/obfuscator/ast"
+	"github.com/devOpifex/obfuscator/catalogue"
+	"github.com/devOpifex/obfuscator/environment"
+)
+
+// Generic is an S3 generic, its methods are named
+// after it so they are renamed (or left as is) together.
+type Generic struct {
+	Name string
+	// dispatched with UseMethod in the project
+	Project bool
+	Methods []*Method
+}
+
+// Method is an S3 method: generic.class
+type Method struct {
+	Generic *Generic
+	Class   string
+	Binding *environment.Binding
+	// class defined in the project, see RenameClasses
+	class *Class
+}
+
+// Mask returns the name of the generic in the obfuscated code,
+// generics of other packages keep their name.
+func (g *Generic) Mask() string {
+	if !g.Project || startWithDot(g.Name) {
+		return g.Name
+	}
+
+	return environment.Mask(g.Name)
+}
+
+// Mask returns the name of the method in the obfuscated code:
+// the masked generic followed by the class.
+func (m *Method) Mask() string {
+	if m.class != nil {
+		return m.Generic.Mask() + "." + m.class.Mask()
+	}
+
+	return m.Generic.Mask() + "." + m.Class
+}
+
+// DeclareMethod registers a method declared outside of the code,
+// e.g.: S3method(generic, class) in the NAMESPACE,
+// it must be called before Run.
+func (b *Binder) DeclareMethod(generic, class string) {
+	b.declared[generic+"."+class] = generic
+}
+
+// Method returns the S3 method a binding refers to, if any.
+func (b *Binder) Method(binding *environment.Binding) *Method {
+	if binding == nil {
+		return nil
+	}
+
+	return b.methods[binding]
+}
+
+// Dispatch returns the generic named by the string
+// passed to UseMethod or NextMethod.
+func (b *Binder) Dispatch(str *ast.StringLiteral) *Generic {
+	return b.dispatch[str]
+}
+
+// Generic returns a generic of the registry by name.
+func (b *Binder) Generic(name string) *Generic {
+	return b.generics[name]
+}
+
+// Generics returns the generics that have methods
+// or are dispatched in the project, sorted by name.
+func (b *Binder) Generics() []*Generic {
+	var generics []*Generic
+	for _, g := range b.generics {
+		generics = append(generics, g)
+	}
+
+	sort.Slice(generics, func(i, j int) bool {
+		return generics[i].Name < generics[j].Name
+	})
+
+	return generics
+}
+
+// declareDispatch records the generic of a call to UseMethod
+// or NextMethod, without argument UseMethod dispatches
+// on the name of the enclosing function.
+func (b *Binder) declareDispatch(node *ast.CallExpression) {
+	var str *ast.StringLiteral
+	if len(node.Arguments) > 0 && (node.Arguments[0].Name == "" || node.Arguments[0].Name == "generic") {
+		str, _ = node.Arguments[0].Value.(*ast.StringLiteral)
+	}
+
+	name := ""
+	if str != nil {
+		name = str.Str
+	}
+
+	if name == "" && node.Name == "UseMethod" && b.fn != nil {
+		name = b.fn.Name
+	}
+
+	if name == "" {
+		return
+	}
+
+	if str != nil {
+		b.dispatches = append(b.dispatches, dispatchString{name: name, str: str})
+	}
+
+	if node.Name == "UseMethod" {
+		b.dispatched[name] = true
+	}
+}
+
+// registerS3 builds the registry of generics and methods
+// once all the top level functions are declared.
+func (b *Binder) registerS3() {
+	for name := range b.dispatched {
+		binding := b.env.GetBinding(name, false)
+		project := (binding.Defined() && binding.Function) || !catalogue.Has(name)
+		b.generics[name] = &Generic{Name: name, Project: project}
+	}
+
+	for binding := range b.functions {
+		if binding.Kind != environment.Global {
+			continue
+		}
+
+		generic, class := b.splitMethod(binding.Name)
+
+		if generic == nil {
+			continue
+		}
+
+		method := &Method{Generic: generic, Class: class, Binding: binding}
+		generic.Methods = append(generic.Methods, method)
+		b.methods[binding] = method
+	}
+
+	for _, g := range b.generics {
+		sort.Slice(g.Methods, func(i, j int) bool {
+			return g.Methods[i].Class < g.Methods[j].Class
+		})
+	}
+
+	for _, s := range b.dispatches {
+		b.dispatch[s.str] = b.generics[s.name]
+	}
+}
+
+// splitMethod splits the name of a function into generic and class,
+// the longest known generic wins: as.data.frame.invoice is
+// a method of as.data.frame, not of as.
+func (b *Binder) splitMethod(name string) (*Generic, string) {
+	if generic, ok := b.declared[name]; ok {
+		return b.generic(generic), strings.TrimPrefix(name, generic+".")
+	}
+
+	// a generic dispatched in the project is not a method
+	if g, ok := b.generics[name]; ok && g.Project {
+		return nil, ""
+	}
+
+	for i := len(name) - 1; i > 0; i-- {
+		if name[i] != '.' || i == len(name)-1 {
+			continue
+		}
+
+		prefix := name[:i]
+
+		if b.isGeneric(prefix) {
+			return b.generic(prefix), name[i+1:]
+		}
+	}
+
+	return nil, ""
+}
+
+// isGeneric returns whether a name is a generic
+// dispatched in the project, declared, or of base R.
+func (b *Binder) isGeneric(name string) bool {
+	if b.dispatched[name] || catalogue.IsGeneric(name) {
+		return true
+	}
+
+	for _, generic := range b.declared {
+		if generic == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// generic returns a generic of the registry,
+// creating generics of other packages as needed.
+func (b *Binder) generic(name string) *Generic {
+	if g, ok := b.generics[name]; ok {
+		return g
+	}
+
+	g := &Generic{Name: name}
+	b.generics[name] = g
+
+	return g
+}
+
+func startWithDot(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
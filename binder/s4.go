@@ -0,0 +1,379 @@
+package binder
+
+import (
+	"github.com/devOpifex/obfuscator/ast"
+	"github.com/devOpifex/obfuscator/catalogue"
+	"github.com/devOpifex/obfuscator/environment"
+)
+
+// S4 is a name of the S4 system defined in the project:
+// a class, a generic or a slot.
+type S4 struct {
+	Name      string
+	protected bool
+}
+
+// Mask returns the name in the obfuscated code.
+func (s *S4) Mask() string {
+	if s.protected {
+		return s.Name
+	}
+
+	return environment.Mask(s.Name)
+}
+
+// functions of the methods package whose strings
+// name classes or generics, e.g.: new("Person"), is(x, "Person")
+var s4API = map[string]bool{
+	"setClass":         true,
+	"setClassUnion":    true,
+	"setGeneric":       true,
+	"setMethod":        true,
+	"setReplaceMethod": true,
+	"setValidity":      true,
+	"setAs":            true,
+	"setRefClass":      true,
+	"standardGeneric":  true,
+	"signature":        true,
+	"representation":   true,
+	"new":              true,
+	"is":               true,
+	"as":               true,
+	"extends":          true,
+	"isVirtualClass":   true,
+	"existsMethod":     true,
+	"hasMethod":        true,
+	"getMethod":        true,
+	"selectMethod":     true,
+	"showMethods":      true,
+	"removeMethod":     true,
+	"isGeneric":        true,
+	"getGenerics":      true,
+	"getClass":         true,
+	"getClassDef":      true,
+	"getValidity":      true,
+	"slotNames":        true,
+	"getSlots":         true,
+	"removeClass":      true,
+}
+
+// calls whose named arguments are slots
+var slotArguments = map[string]bool{
+	"new":            true,
+	"initialize":     true,
+	"callNextMethod": true,
+}
+
+// S4String returns the class, generic or slot a string names.
+func (b *Binder) S4String(str *ast.StringLiteral) *S4 {
+	return b.s4Strings[str]
+}
+
+// Slot returns the slot accessed with @, the node is
+// the right hand side, or the slot defined or set by a named argument.
+func (b *Binder) Slot(node ast.Node) *S4 {
+	return b.slotNodes[node]
+}
+
+// SlotArgument returns the slot a named argument refers to,
+// e.g.: new("Person", name = "Alice")
+func (b *Binder) SlotArgument(arg *ast.Argument) *S4 {
+	return b.slotArgs[arg]
+}
+
+// S4Class returns a class defined with setClass by name.
+func (b *Binder) S4Class(name string) *S4 {
+	return b.s4Classes[name]
+}
+
+// declareS4 records the classes, generics and slots defined
+// by a call to the S4 API, and the strings that may refer to them.
+func (b *Binder) declareS4(node *ast.CallExpression) {
+	if !s4API[node.Name] {
+		return
+	}
+
+	for _, a := range node.Arguments {
+		for _, str := range classStrings(a.Value) {
+			b.s4Candidates = append(b.s4Candidates, str)
+		}
+	}
+
+	switch node.Name {
+	case "setClass", "setClassUnion", "setRefClass":
+		class := stringArgument(node, 0, "Class")
+		if class == nil {
+			return
+		}
+
+		b.s4Classes[class.Str] = &S4{Name: class.Str}
+
+		if node.Name == "setClassUnion" {
+			return
+		}
+
+		for _, a := range node.Arguments {
+			switch a.Name {
+			case "slots", "prototype", "representation":
+				b.declareSlots(class.Str, a.Value)
+			case "":
+				// setClass("Person", representation(name = "character"))
+				if call, ok := a.Value.(*ast.CallExpression); ok && (call.Name == "representation" || call.Name == "prototype") {
+					b.declareSlots(class.Str, call)
+				}
+			}
+		}
+
+	case "setGeneric":
+		generic := stringArgument(node, 0, "name")
+		if generic == nil || catalogue.Has(generic.Str) {
+			return
+		}
+
+		b.s4Generics[generic.Str] = &S4{Name: generic.Str}
+		b.bind(b.env, generic.Str, environment.Global, true)
+
+		if def := argument(node, 1, "def"); def != nil {
+			if fn, ok := def.Value.(*ast.FunctionLiteral); ok {
+				b.s4Definitions[generic.Str] = fn
+			}
+		}
+	}
+}
+
+// declareSlots records slots = c(name = "character"),
+// prototype = list(name = "") or slots = c("name", "age")
+func (b *Binder) declareSlots(class string, node ast.Node) {
+	call, ok := node.(*ast.CallExpression)
+
+	if !ok {
+		return
+	}
+
+	for _, a := range call.Arguments {
+		// e.g.: .Data
+		if startWithDot(a.Name) {
+			continue
+		}
+
+		if a.Name != "" {
+			b.slotArgs[a] = b.declareSlot(class, a.Name)
+			continue
+		}
+
+		if str, ok := a.Value.(*ast.StringLiteral); ok && (call.Name == "c" || call.Name == "list") && !startWithDot(str.Str) {
+			b.s4Strings[str] = b.declareSlot(class, str.Str)
+		}
+	}
+}
+
+func (b *Binder) declareSlot(class, name string) *S4 {
+	slot, ok := b.slots[name]
+
+	if !ok {
+		slot = &S4{Name: name}
+		b.slots[name] = slot
+	}
+
+	// slots of a protected class are protected
+	if environment.IsProtected(class) {
+		slot.protected = true
+	}
+
+	return slot
+}
+
+// registerS4 resolves the strings of the S4 API
+// once all classes and generics are declared.
+func (b *Binder) registerS4() {
+	for _, str := range b.s4Candidates {
+		if _, ok := b.s4Strings[str]; ok {
+			continue
+		}
+
+		if class, ok := b.s4Classes[str.Str]; ok {
+			b.s4Strings[str] = class
+			continue
+		}
+
+		if generic, ok := b.s4Generics[str.Str]; ok {
+			b.s4Strings[str] = generic
+			continue
+		}
+
+		// setMethod("area", ...) on a function of the project
+		if binding := b.env.GetBinding(str.Str, false); binding.Defined() && binding.Function && !startWithDot(str.Str) {
+			b.s4Strings[str] = &S4{Name: str.Str}
+		}
+	}
+}
+
+// resolveS4Call renames the slots passed to new(), generators
+// and callNextMethod(), and protects the formals of methods
+// of generics from other packages.
+func (b *Binder) resolveS4Call(node *ast.CallExpression, callee *environment.Binding) {
+	if slotArguments[node.Name] || b.generators[callee] {
+		for _, a := range node.Arguments {
+			if slot, ok := b.slots[a.Name]; ok {
+				b.slotArgs[a] = slot
+			}
+		}
+	}
+
+	// fields of reference classes: Account(balance = 0)
+	// or new("Account", balance = 0)
+	if _, ok := b.refGenerators[callee]; ok {
+		b.fieldArguments(node)
+	}
+
+	if class := stringArgument(node, 0, "Class"); node.Name == "new" && class != nil && b.refNames[class.Str] != nil {
+		b.fieldArguments(node)
+	}
+
+	if node.Name == "slot" {
+		if str := stringArgument(node, 1, "name"); str != nil {
+			if slot, ok := b.slots[str.Str]; ok {
+				b.s4Strings[str] = slot
+			}
+		}
+	}
+
+	if node.Name != "setMethod" && node.Name != "setReplaceMethod" {
+		return
+	}
+
+	generic := stringArgument(node, 0, "f")
+	if generic == nil {
+		return
+	}
+
+	if _, ok := b.s4Generics[generic.Str]; ok {
+		b.signatureArguments(node, b.s4Definitions[generic.Str])
+		return
+	}
+
+	if binding := b.env.GetBinding(generic.Str, false); binding.Defined() && binding.Function {
+		b.signatureArguments(node, b.functions[binding])
+		return
+	}
+
+	// method of a generic of another package, e.g.: show
+	// its formals must match those of the generic
+	for _, a := range node.Arguments {
+		fn, ok := a.Value.(*ast.FunctionLiteral)
+
+		if !ok {
+			continue
+		}
+
+		for _, p := range fn.Parameters {
+			if binding := b.arguments[p]; binding != nil {
+				binding.Protected = true
+			}
+		}
+	}
+}
+
+// signatureArguments matches the names of the signature of a method
+// with the formals of its generic: signature(shape = "Circle")
+func (b *Binder) signatureArguments(node *ast.CallExpression, generic *ast.FunctionLiteral) {
+	scope, ok := b.scopes[generic]
+
+	if !ok {
+		return
+	}
+
+	a := argument(node, 1, "signature")
+
+	if a == nil {
+		return
+	}
+
+	signature, ok := a.Value.(*ast.CallExpression)
+
+	if !ok || (signature.Name != "signature" && signature.Name != "c") {
+		return
+	}
+
+	for _, a := range signature.Arguments {
+		if formal := scope.GetBinding(a.Name, false); a.Name != "" && formal != nil && formal.Kind == environment.Parameter {
+			b.arguments[a] = formal
+		}
+	}
+}
+
+// resolveSlot records the slot accessed with @
+func (b *Binder) resolveSlot(node *ast.InfixExpression) {
+	ident, ok := node.Right.(*ast.Identifier)
+
+	if !ok {
+		return
+	}
+
+	if slot, ok := b.slots[ident.Value]; ok {
+		b.slotNodes[ident] = slot
+	}
+}
+
+// declareGenerator records Person <- setClass("Person", ...),
+// named arguments of calls to Person are slots.
+func (b *Binder) declareGenerator(node *ast.InfixExpression, env *environment.Environment, kind environment.Kind) {
+	ident, ok := node.Left.(*ast.Identifier)
+
+	if !ok || !isAssign(node.Operator) {
+		return
+	}
+
+	right := node.Right
+	if ns, ok := right.(*ast.InfixExpression); ok && ns.Operator == "::" {
+		right = ns.Right
+	}
+
+	call, ok := right.(*ast.CallExpression)
+
+	if !ok || (call.Name != "setClass" && call.Name != "setRefClass") {
+		return
+	}
+
+	binding := b.bind(env, ident.Value, kind, true)
+	b.generators[binding] = true
+
+	if call.Name == "setRefClass" {
+		b.refGenerators[binding] = b.declareRefClass(call, env)
+	}
+}
+
+// stringArgument returns the string passed at a position or by name
+func stringArgument(node *ast.CallExpression, position int, name string) *ast.StringLiteral {
+	a := argument(node, position, name)
+
+	if a == nil {
+		return nil
+	}
+
+	str, _ := a.Value.(*ast.StringLiteral)
+	return str
+}
+
+// argument returns the argument passed at a position or by name
+func argument(node *ast.CallExpression, position int, name string) *ast.Argument {
+	for _, a := range node.Arguments {
+		if a.Name == name {
+			return a
+		}
+	}
+
+	i := 0
+	for _, a := range node.Arguments {
+		if a.Name != "" {
+			continue
+		}
+
+		if i == position {
+			return a
+		}
+		i++
+	}
+
+	return nil
+}
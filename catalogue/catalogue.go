@@ -0,0 +1,136 @@
+// Package catalogue lists the symbols exported by base R
+// and the packages attached by default (stats, utils, methods,
+// graphics, grDevices).
+//
+// The lists are embedded in the binary, one symbol per line
+// in packages/<package>.txt, the S3 generics of these packages
+// are listed in generics.txt and the built-in classes
+// (and classes of common packages) in classes.txt.
+//
+// The package lists are written by hand, they are not yet the
+// output of generate.R: base.txt lacks most of the S3 methods
+// of base (e.g.: print.data.frame, [.factor, Ops.Date) and the
+// symbols added after R 4.4, the other lists are not checked
+// against getNamespaceExports(). Run go generate with R installed
+// to replace them.
+package catalogue
+
+//go:generate Rscript generate.R
+
+import (
+	"bufio"
+	"embed"
+	"sort"
+	"strings"
+)
+
+//go:embed packages/*.txt
+var files embed.FS
+
+//go:embed generics.txt
+var genericsFile string
+
+//go:embed classes.txt
+var classesFile string
+
+// order in which R attaches the packages, the first match wins
+// as in the search path.
+var search = []string{
+	"stats",
+	"graphics",
+	"grDevices",
+	"utils",
+	"methods",
+	"base",
+}
+
+var symbols map[string]string
+var generics map[string]bool
+var classes map[string]bool
+
+func init() {
+	symbols = make(map[string]string)
+	generics = lines(genericsFile)
+	classes = lines(classesFile)
+
+	for i := len(search) - 1; i >= 0; i-- {
+		f, err := files.Open("packages/" + search[i] + ".txt")
+
+		if err != nil {
+			panic(err)
+		}
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			name := strings.TrimSpace(scanner.Text())
+
+			if name == "" {
+				continue
+			}
+
+			symbols[name] = search[i]
+		}
+
+		f.Close()
+	}
+}
+
+func lines(content string) map[string]bool {
+	set := make(map[string]bool)
+
+	for _, name := range strings.Split(content, "\n") {
+		if name = strings.TrimSpace(name); name != "" {
+			set[name] = true
+		}
+	}
+
+	return set
+}
+
+// Lookup returns the package exporting a symbol,
+// the second value is false when the symbol is not in the catalogue.
+func Lookup(name string) (string, bool) {
+	pkg, ok := symbols[name]
+	return pkg, ok
+}
+
+// Has returns whether a symbol is exported by base R
+// or one of the default packages.
+func Has(name string) bool {
+	_, ok := symbols[name]
+	return ok
+}
+
+// IsGeneric returns whether a symbol is an S3 generic
+// (or an internal generic) of base R or a default package.
+func IsGeneric(name string) bool {
+	return generics[name]
+}
+
+// IsClass returns whether a class is built in R
+// or defined by a common package, e.g.: data.frame, error, tbl_df
+func IsClass(name string) bool {
+	return classes[name]
+}
+
+// Packages returns the packages of the catalogue
+// in search path order.
+func Packages() []string {
+	return append([]string{}, search...)
+}
+
+// Symbols returns the sorted symbols Lookup attributes to a package,
+// symbols exported by several packages belong to the first on the search path.
+func Symbols(pkg string) []string {
+	var out []string
+
+	for name, p := range symbols {
+		if p == pkg {
+			out = append(out, name)
+		}
+	}
+
+	sort.Strings(out)
+
+	return out
+}
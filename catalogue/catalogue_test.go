@@ -0,0 +1,81 @@
+package catalogue
+
+import "testing"
+
+func TestLookup(t *testing.T) {
+	expected := map[string]string{
+		"c":           "base",
+		"paste0":      "base",
+		"filter":      "stats",
+		"head":        "utils",
+		"setClass":    "methods",
+		"plot":        "graphics",
+		"rgb":         "grDevices",
+		"UseMethod":   "base",
+		"read.csv":    "utils",
+		"model.frame": "stats",
+		"inherits":    "base",
+		"substitute":  "base",
+		"mget":        "base",
+		"Position":    "base",
+		"return":      "base",
+		"function":    "base",
+		"names<-":     "base",
+		"require":     "base",
+		"close":       "base",
+		"serialize":   "base",
+		"q":           "base",
+	}
+
+	for name, pkg := range expected {
+		got, ok := Lookup(name)
+
+		if !ok {
+			t.Fatalf("expected %v in the catalogue", name)
+		}
+
+		if got != pkg {
+			t.Fatalf("expected %v in %v, got %v", name, pkg, got)
+		}
+	}
+
+	if Has("notInBaseR") {
+		t.Fatal("unexpected symbol in the catalogue")
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	for _, pkg := range Packages() {
+		if len(Symbols(pkg)) == 0 {
+			t.Fatalf("no symbol in %v", pkg)
+		}
+	}
+}
+
+func TestGenerics(t *testing.T) {
+	for _, name := range []string{"print", "format", "as.data.frame", "summary", "predict"} {
+		if !IsGeneric(name) {
+			t.Fatalf("expected %v to be a generic", name)
+		}
+
+		if !Has(name) {
+			t.Fatalf("generic %v not in the catalogue", name)
+		}
+	}
+
+	if IsGeneric("paste0") {
+		t.Fatal("paste0 is not a generic")
+	}
+}
+
+func TestClasses(t *testing.T) {
+	for _, name := range []string{"list", "data.frame", "error", "condition", "Date"} {
+		if !IsClass(name) {
+			t.Fatalf("expected %v to be a class", name)
+		}
+	}
+
+	if IsClass("invoice") {
+		t.Fatal("invoice is not a built-in class")
+	}
+}
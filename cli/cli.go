@@ -6,13 +6,19 @@ import (
 )
 
 type CLI struct {
-	In          *string
-	Out         *string
-	License     *string
-	Key         *string
-	Protect     *string
-	Ignore      []string
-	Deobfuscate *bool
+	In              *string
+	Out             *string
+	License         *string
+	Key             *string
+	Protect         *string
+	Ignore          []string
+	Deobfuscate     *bool
+	Classes         *bool
+	Members         *bool
+	Elements        *bool
+	ProtectElements []string
+	Masking         []string
+	Environments    *bool
 }
 
 func Cli() CLI {
@@ -23,17 +29,29 @@ func Cli() CLI {
 	protect := flag.String("protect", "", "Comma separated protected tokens, e.g.: foo,bar")
 	ignore := flag.String("ignore", "", "Comma separated directories to ignore, e.g.: renv")
 	deobfuscate := flag.Bool("deobfuscate", false, "Deobfuscate the obfuscated files")
+	classes := flag.Bool("classes", false, "Rename the S3 and condition classes defined in the files")
+	members := flag.Bool("protect-members", false, "Keep the names of public members of classes, e.g.: R6")
+	elements := flag.Bool("elements", false, "Rename the list elements and columns created in the files")
+	environments := flag.Bool("environments", false, "Rename the members of environments created with new.env() in the files")
+	masking := flag.String("masking", "", "Comma separated data-masking functions whose data is the first argument, e.g.: my_filter")
+	protectElements := flag.String("protect-elements", "", "Comma separated elements to keep with -elements, e.g.: id,name")
 
 	flag.Parse()
 
 	return CLI{
-		In:          in,
-		Out:         out,
-		Key:         key,
-		License:     license,
-		Protect:     protect,
-		Ignore:      ignoreToSlice(*ignore),
-		Deobfuscate: deobfuscate,
+		In:              in,
+		Out:             out,
+		Key:             key,
+		License:         license,
+		Protect:         protect,
+		Ignore:          ignoreToSlice(*ignore),
+		Deobfuscate:     deobfuscate,
+		Classes:         classes,
+		Members:         members,
+		Elements:        elements,
+		ProtectElements: ignoreToSlice(*protectElements),
+		Masking:         ignoreToSlice(*masking),
+		Environments:    environments,
 	}
 }
 
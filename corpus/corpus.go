@@ -0,0 +1,119 @@
+// Package corpus loads the vendored R snippets used to check
+// that the parser and the transpiler handle real-world code.
+package corpus
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Snippet is a single R file of the corpus along with
+// the outcome we expect from it.
+// The construct is the name of the directory the snippet
+// lives in, e.g.: base, tidyverse, r6.
+type Snippet struct {
+	Path      string
+	Construct string
+	Parse     bool
+	Run       bool
+	Requires  []string
+	Code      string
+}
+
+// Snippets are sorted by path
+type Snippets []Snippet
+
+// Load reads every .R file under root,
+// expectations are read from the header comments:
+//
+//	# parse: ok | error
+//	# run: equivalent | differs
+//	# requires: pkg1, pkg2
+func Load(root string) (Snippets, error) {
+	var snippets Snippets
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || filepath.Ext(path) != ".R" {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+
+		if err != nil {
+			return err
+		}
+
+		s := Snippet{
+			Path:      filepath.ToSlash(rel),
+			Construct: strings.Split(filepath.ToSlash(rel), "/")[0],
+			Code:      string(content),
+		}
+		s.readHeader(content)
+
+		snippets = append(snippets, s)
+
+		return nil
+	})
+
+	return snippets, err
+}
+
+func (s *Snippet) readHeader(content []byte) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if !strings.HasPrefix(line, "#") {
+			return
+		}
+
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "#"), ":")
+
+		if !ok {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+
+		switch strings.TrimSpace(key) {
+		case "parse":
+			s.Parse = value == "ok"
+		case "run":
+			s.Run = value == "equivalent"
+		case "requires":
+			for _, pkg := range strings.Split(value, ",") {
+				s.Requires = append(s.Requires, strings.TrimSpace(pkg))
+			}
+		}
+	}
+}
+
+// Constructs returns the distinct constructs in order of appearance
+func (s Snippets) Constructs() []string {
+	var constructs []string
+	seen := make(map[string]bool)
+
+	for _, snippet := range s {
+		if seen[snippet.Construct] {
+			continue
+		}
+		seen[snippet.Construct] = true
+		constructs = append(constructs, snippet.Construct)
+	}
+
+	return constructs
+}
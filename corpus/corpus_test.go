@@ -0,0 +1,174 @@
+package corpus
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/devOpifex/obfuscator/binder"
+	"github.com/devOpifex/obfuscator/environment"
+	"github.com/devOpifex/obfuscator/lexer"
+	"github.com/devOpifex/obfuscator/parser"
+	"github.com/devOpifex/obfuscator/r"
+	"github.com/devOpifex/obfuscator/transpiler"
+)
+
+type rate struct {
+	total  int
+	parsed int
+	ran    int
+	equal  int
+}
+
+func TestCorpus(t *testing.T) {
+	snippets, err := Load("snippets")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(snippets) == 0 {
+		t.Fatal("no snippets found")
+	}
+
+	_, rErr := exec.LookPath("R")
+	hasR := rErr == nil
+
+	rates := make(map[string]*rate)
+	for _, s := range snippets {
+		if rates[s.Construct] == nil {
+			rates[s.Construct] = &rate{}
+		}
+		rt := rates[s.Construct]
+		rt.total++
+
+		code, ok := obfuscate(s)
+
+		if ok != s.Parse {
+			t.Errorf("%v: expected parse %v, got %v", s.Path, expectParse(s.Parse), expectParse(ok))
+		}
+
+		if !ok {
+			continue
+		}
+		rt.parsed++
+
+		if !hasR || !installed(s.Requires) {
+			continue
+		}
+		rt.ran++
+
+		equal := sameOutput(t, s.Code, code)
+
+		if equal {
+			rt.equal++
+		}
+
+		if equal != s.Run {
+			t.Errorf("%v: expected run %v, got %v", s.Path, expectRun(s.Run), expectRun(equal))
+		}
+	}
+
+	for _, c := range snippets.Constructs() {
+		rt := rates[c]
+		t.Logf(
+			"%-10s parse %v/%v (%.0f%%) run %v/%v",
+			c, rt.parsed, rt.total, 100*float64(rt.parsed)/float64(rt.total), rt.equal, rt.ran,
+		)
+	}
+
+	if !hasR {
+		t.Log("R not found: obfuscated code was not run")
+	}
+}
+
+// obfuscate runs the snippet through the whole pipeline,
+// returns false if it failed to lex or parse.
+func obfuscate(s Snippet) (string, bool) {
+	l := lexer.NewCode(s.Path, s.Code)
+	l.Run()
+
+	if l.HasError() {
+		return "", false
+	}
+
+	p := parser.New(l)
+	p.Run()
+
+	if p.HasError() {
+		return "", false
+	}
+
+	env := environment.New()
+	env.SetPaths(l.Files)
+
+	b := binder.New(env, p.Files())
+	b.Run()
+
+	ts := transpiler.New(env, b, p.Files())
+	ts.Run()
+
+	var code strings.Builder
+	for _, t := range ts {
+		code.WriteString(t.GetCode())
+	}
+
+	return code.String(), true
+}
+
+func installed(pkgs []string) bool {
+	for _, pkg := range pkgs {
+		if !r.IsPackage(pkg) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sameOutput(t *testing.T, original, obfuscated string) bool {
+	dir := t.TempDir()
+
+	want, err := source(dir, "original.R", original)
+
+	if err != nil {
+		t.Logf("original failed to run: %v", err)
+		return false
+	}
+
+	got, err := source(dir, "obfuscated.R", obfuscated)
+
+	if err != nil {
+		return false
+	}
+
+	return string(want) == string(got)
+}
+
+func source(dir, name, code string) ([]byte, error) {
+	path := filepath.ToSlash(filepath.Join(dir, name))
+
+	if err := os.WriteFile(path, []byte(code), 0644); err != nil {
+		return nil, err
+	}
+
+	return r.Call("source('" + path + "')")
+}
+
+func expectParse(ok bool) string {
+	if ok {
+		return "ok"
+	}
+
+	return "error"
+}
+
+func expectRun(ok bool) string {
+	if ok {
+		return "equivalent"
+	}
+
+	return "differs"
+}
@@ -0,0 +1,179 @@
+// Package description reads and rewrites the DESCRIPTION file of an R package.
+//
+// The file is made of fields, e.g.: Package: billing, a field
+// continues on the following lines that start with a space or a tab.
+// Fields left unchanged are written back as is.
+package description
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Field is a field of the DESCRIPTION, e.g.: Collate
+type Field struct {
+	Name string
+	// value as written, including continuation lines
+	Value string
+	// position of the field in the source
+	start   int
+	end     int
+	changed bool
+}
+
+type Description struct {
+	source string
+	Fields []*Field
+}
+
+// Parse reads the fields of a DESCRIPTION file.
+func Parse(source string) *Description {
+	d := &Description{source: source}
+
+	var field *Field
+	for pos := 0; pos < len(source); {
+		end := strings.IndexByte(source[pos:], '\n')
+
+		if end < 0 {
+			end = len(source)
+		} else {
+			end += pos
+		}
+
+		line := strings.TrimRight(source[pos:end], "\r")
+		start := pos
+		pos = end + 1
+
+		if strings.TrimSpace(line) == "" {
+			field = nil
+			continue
+		}
+
+		if line[0] == ' ' || line[0] == '\t' {
+			if field != nil {
+				field.Value += "\n" + line
+				field.end = start + len(line)
+			}
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+
+		if !ok {
+			field = nil
+			continue
+		}
+
+		field = &Field{
+			Name:  name,
+			Value: strings.TrimSpace(value),
+			start: start,
+			end:   start + len(line),
+		}
+		d.Fields = append(d.Fields, field)
+	}
+
+	return d
+}
+
+// Get returns the value of a field, an empty string if there is none.
+func (d *Description) Get(name string) string {
+	if f := d.field(name); f != nil {
+		return f.Value
+	}
+
+	return ""
+}
+
+// Set replaces the value of a field, the field is added if there is none.
+func (d *Description) Set(name, value string) {
+	f := d.field(name)
+
+	if f == nil {
+		f = &Field{Name: name, start: -1}
+		d.Fields = append(d.Fields, f)
+	}
+
+	f.Value = value
+	f.changed = true
+}
+
+func (d *Description) field(name string) *Field {
+	for _, f := range d.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+
+	return nil
+}
+
+func (f *Field) String() string {
+	if strings.HasPrefix(f.Value, "\n") {
+		return f.Name + ":" + f.Value
+	}
+
+	return f.Name + ": " + f.Value
+}
+
+// String returns the DESCRIPTION with the fields rewritten,
+// fields left unchanged are written as in the source,
+// fields added are written at the end.
+func (d *Description) String() string {
+	var out bytes.Buffer
+
+	pos := 0
+	for _, f := range d.Fields {
+		if !f.changed || f.start < 0 {
+			continue
+		}
+
+		out.WriteString(d.source[pos:f.start])
+		out.WriteString(f.String())
+		pos = f.end
+	}
+
+	out.WriteString(d.source[pos:])
+
+	for _, f := range d.Fields {
+		if f.start >= 0 {
+			continue
+		}
+
+		if out.Len() > 0 && !bytes.HasSuffix(out.Bytes(), []byte("\n")) {
+			out.WriteString("\n")
+		}
+
+		out.WriteString(f.String() + "\n")
+	}
+
+	return out.String()
+}
+
+// Split returns the files of a Collate field,
+// separated by spaces or newlines and quoted or not.
+func Split(value string) []string {
+	var files []string
+
+	for _, f := range strings.Fields(value) {
+		if len(f) > 1 && (f[0] == '\'' || f[0] == '"') && f[len(f)-1] == f[0] {
+			f = f[1 : len(f)-1]
+		}
+
+		files = append(files, f)
+	}
+
+	return files
+}
+
+// Join returns the value of a Collate field listing files,
+// one per line as roxygen writes it.
+func Join(files []string) string {
+	var value strings.Builder
+
+	for _, f := range files {
+		value.WriteString("\n    '" + f + "'")
+	}
+
+	return value.String()
+}
@@ -0,0 +1,61 @@
+package description
+
+import "testing"
+
+const source = `Package: billing
+Title: Invoices
+Version: 0.1.0
+Description: Create and send
+    invoices.
+Imports:
+    methods,
+    R6
+`
+
+func TestParse(t *testing.T) {
+	d := Parse(source)
+
+	if d.Get("Package") != "billing" {
+		t.Fatalf("expected billing, got %v", d.Get("Package"))
+	}
+
+	if d.Get("Description") != "Create and send\n    invoices." {
+		t.Fatalf("unexpected Description %q", d.Get("Description"))
+	}
+
+	if d.Get("Imports") != "\n    methods,\n    R6" {
+		t.Fatalf("unexpected Imports %q", d.Get("Imports"))
+	}
+
+	if d.Get("Collate") != "" {
+		t.Fatalf("expected no Collate, got %v", d.Get("Collate"))
+	}
+}
+
+func TestSet(t *testing.T) {
+	d := Parse(source + "Collate: 'b.R' a.R\n    \"c.R\"\nEncoding: UTF-8\n")
+
+	files := Split(d.Get("Collate"))
+
+	if len(files) != 3 || files[0] != "b.R" || files[1] != "a.R" || files[2] != "c.R" {
+		t.Fatalf("unexpected Collate %v", files)
+	}
+
+	d.Set("Collate", Join([]string{"x.R", "y.R"}))
+	d.Set("Config/Needs", "obfuscated")
+
+	expected := source + `Collate:
+    'x.R'
+    'y.R'
+Encoding: UTF-8
+Config/Needs: obfuscated
+`
+
+	if d.String() != expected {
+		t.Fatalf("unexpected DESCRIPTION\n%v", d.String())
+	}
+
+	if Parse(source).String() != source {
+		t.Fatal("DESCRIPTION was not written as is")
+	}
+}
@@ -0,0 +1,113 @@
+package environment
+
+import "sort"
+
+// Kind is what a binding refers to
+type Kind int
+
+const (
+	// defined at the top level of the project
+	Global Kind = iota
+	// assigned within a function
+	Local
+	// formal argument of a function
+	Parameter
+	// free identifier, not defined in the project, e.g.: base R
+	External
+)
+
+// Binding is what an identifier resolves to,
+// every occurrence of the same binding shares its ID.
+type Binding struct {
+	ID       int
+	Name     string
+	Kind     Kind
+	Function bool
+	// package exporting an external binding, e.g.: base, stats
+	Package string
+	// defined in the project but its name must be kept,
+	// e.g.: formals of a method of a generic from another package
+	Protected bool
+	// only assigned values that are not functions, e.g.: x <- c(1, 2)
+	Data bool
+}
+
+func (k Kind) String() string {
+	switch k {
+	case Global:
+		return "global"
+	case Local:
+		return "local"
+	case Parameter:
+		return "parameter"
+	}
+
+	return "external"
+}
+
+// Defined returns whether the binding is defined in the project
+// and should therefore be obfuscated.
+func (b *Binding) Defined() bool {
+	return b != nil && b.Kind != External
+}
+
+func (e *Environment) SetBinding(b *Binding) {
+	if e.bindings == nil {
+		e.bindings = make(map[string]*Binding)
+	}
+
+	e.bindings[b.Name] = b
+}
+
+func (e *Environment) GetBinding(name string, outer bool) *Binding {
+	for env := e; env != nil; env = env.outer {
+		if b, ok := env.bindings[name]; ok {
+			return b
+		}
+
+		if !outer {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// GetFunctionBinding resolves a name in call position,
+// like R it skips bindings that are not functions: those
+// whose value is unknown, e.g.: parameters, may be functions.
+func (e *Environment) GetFunctionBinding(name string) *Binding {
+	for env := e; env != nil; env = env.outer {
+		if b, ok := env.bindings[name]; ok && (b.Function || !b.Data) {
+			return b
+		}
+	}
+
+	return nil
+}
+
+// GetSuperBinding resolves the target of a super assignment (<<-):
+// the search starts in the enclosing environment,
+// at the top level it is the global environment itself.
+func (e *Environment) GetSuperBinding(name string) *Binding {
+	if e.outer == nil {
+		return e.GetBinding(name, false)
+	}
+
+	return e.outer.GetBinding(name, true)
+}
+
+// Bindings returns the bindings of the environment, not of its outer
+// environments, in the order they were created.
+func (e *Environment) Bindings() []*Binding {
+	var bindings []*Binding
+	for _, b := range e.bindings {
+		bindings = append(bindings, b)
+	}
+
+	sort.Slice(bindings, func(i, j int) bool {
+		return bindings[i].ID < bindings[j].ID
+	})
+
+	return bindings
+}
@@ -1,6 +1,7 @@
 package environment
 
 import (
+	"path/filepath"
 	"strings"
 
 	"github.com/devOpifex/obfuscator/lexer"
@@ -10,13 +11,44 @@ var KEY string = "DEFAULT"
 var PROTECT []string
 var DEOBFUSCATE bool = false
 
+// protected is the set of PROTECT
+// masks caches the result of Mask for the current KEY
+// taken holds the names left as is in the output, see Reserve
+var protected = make(map[string]bool)
+var masks = make(map[string]string)
+var taken = make(map[string]bool)
+
+// reserved words of R, a masked name can be none of these
+var reserved = map[string]bool{
+	"if":            true,
+	"else":          true,
+	"repeat":        true,
+	"while":         true,
+	"function":      true,
+	"for":           true,
+	"in":            true,
+	"next":          true,
+	"break":         true,
+	"TRUE":          true,
+	"FALSE":         true,
+	"NULL":          true,
+	"Inf":           true,
+	"NaN":           true,
+	"NA":            true,
+	"NA_integer_":   true,
+	"NA_real_":      true,
+	"NA_character_": true,
+	"NA_complex_":   true,
+	"T":             true,
+	"F":             true,
+}
+
+// maps are allocated on first write:
+// most enclosed environments hold few or no names
 type Environment struct {
-	variables []string
-	functions []string
-	arguments []string
-	generics  []string
-	paths     []string
-	outer     *Environment
+	paths    map[string]bool
+	bindings map[string]*Binding
+	outer    *Environment
 }
 
 func Enclose(outer *Environment) *Environment {
@@ -25,64 +57,56 @@ func Enclose(outer *Environment) *Environment {
 	return env
 }
 
-func Open(env *Environment) *Environment {
-	return env.outer
-}
-
 func Define(key string, protect string, deobfuscate bool) {
 	KEY = key
 	PROTECT = strings.Split(protect, ",")
 	DEOBFUSCATE = deobfuscate
-}
 
-func New() *Environment {
-	return &Environment{
-		outer: nil,
+	protected = make(map[string]bool)
+	for _, p := range PROTECT {
+		protected[p] = true
 	}
+
+	masks = make(map[string]string)
+	taken = make(map[string]bool)
 }
 
-func (e *Environment) GetVariable(name string, outer bool) bool {
-	for _, v := range e.variables {
-		if v == name {
-			return true
+// Reserve marks names that are not obfuscated, e.g.: base R functions
+// used in the project, so that no masked name collides with them.
+func Reserve(names ...string) {
+	for _, n := range names {
+		if taken[n] {
+			continue
 		}
-	}
-
-	if e.outer != nil && outer {
-		return e.outer.GetVariable(name, outer)
-	}
-
-	return false
-}
 
-func (e *Environment) SetVariable(name string) {
-	if e.GetVariable(name, false) {
-		return
+		taken[n] = true
+		masks = make(map[string]string)
 	}
-
-	e.variables = append(e.variables, name)
 }
 
-func (e *Environment) GetFunction(name string) bool {
-	for _, f := range e.functions {
-		if f == name {
-			return true
+// Protect adds names to PROTECT, e.g.: the exports of a package
+func Protect(names ...string) {
+	for _, n := range names {
+		if protected[n] {
+			continue
 		}
-	}
 
-	if e.outer != nil {
-		return e.outer.GetFunction(name)
+		PROTECT = append(PROTECT, n)
+		protected[n] = true
+		masks = make(map[string]string)
 	}
+}
 
-	return false
+// isTaken returns whether a masked name would clash
+// with a reserved word or a name that is not obfuscated
+func isTaken(name string) bool {
+	return reserved[name] || protected[name] || taken[name]
 }
 
-func (e *Environment) SetFunction(name string) {
-	if e.GetFunction(name) {
-		return
+func New() *Environment {
+	return &Environment{
+		outer: nil,
 	}
-
-	e.functions = append(e.functions, name)
 }
 
 func (e *Environment) SetPaths(files lexer.Files) {
@@ -95,7 +119,7 @@ func (e *Environment) SetPaths(files lexer.Files) {
 			}
 
 			if i == len(spit)-1 {
-				spit[i] = strings.ReplaceAll(spit[i], ".R", "")
+				spit[i] = strings.TrimSuffix(spit[i], filepath.Ext(spit[i]))
 			}
 			e.setPath(spit[i])
 		}
@@ -103,45 +127,63 @@ func (e *Environment) SetPaths(files lexer.Files) {
 }
 
 func (e *Environment) setPath(name string) {
-	e.paths = append(e.paths, name)
+	if e.paths == nil {
+		e.paths = make(map[string]bool)
+	}
+
+	e.paths[name] = true
 }
 
 func (e *Environment) GetPath(name string) bool {
-	for _, p := range e.paths {
-		if p == name {
-			return true
-		}
-	}
-	return false
+	return e.paths[name]
 }
 
-func isProtected(name string) bool {
-	if len(PROTECT) == 0 {
-		return false
-	}
-
-	for _, p := range PROTECT {
-		if p == name {
-			return true
-		}
-	}
-	return false
+// IsProtected returns whether a name is in PROTECT
+func IsProtected(name string) bool {
+	return protected[name]
 }
 
 func Mask(txt string) string {
-	if isProtected(txt) {
+	if IsProtected(txt) {
 		return txt
 	}
 
+	if masked, ok := masks[txt]; ok {
+		return masked
+	}
+
+	var masked string
 	if DEOBFUSCATE {
-		return decipher(txt, KEY)
+		masked = Unmask(txt)
+	} else {
+		masked = cipherFree(txt)
 	}
 
-	return cipher(txt, KEY)
+	masks[txt] = masked
+
+	return masked
 }
 
 func Unmask(ciphertext string) string {
-	return decipher(ciphertext, KEY)
+	return strings.TrimRight(decipher(ciphertext, KEY), pad)
+}
+
+// pad is appended to a name whose cipher is taken,
+// it is removed when deciphering
+const pad = "\x00"
+
+// cipherFree ciphers a name, padding it until
+// the result clashes with no reserved or unmasked name:
+// the cipher is deterministic so is the resolution.
+func cipherFree(txt string) string {
+	masked := cipher(txt, KEY)
+
+	for padded := txt; isTaken(masked); {
+		padded += pad
+		masked = cipher(padded, KEY)
+	}
+
+	return masked
 }
 
 func cipher(plaintext, secret string) string {
@@ -0,0 +1,208 @@
+package environment
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestEnvironment(t *testing.T) {
+	env := New()
+	env.SetBinding(&Binding{Name: "x", Kind: Global})
+	env.SetBinding(&Binding{Name: "foo", Kind: Global, Function: true})
+
+	inner := Enclose(env)
+	inner.SetBinding(&Binding{Name: "y", Kind: Local})
+
+	if inner.GetBinding("x", true) == nil {
+		t.Fatal("x not found in outer environment")
+	}
+
+	if inner.GetBinding("x", false) != nil {
+		t.Fatal("x found without looking in outer environment")
+	}
+
+	if env.GetBinding("y", true) != nil {
+		t.Fatal("y leaked to the outer environment")
+	}
+
+	if inner.GetFunctionBinding("foo") == nil {
+		t.Fatal("foo not found in outer environment")
+	}
+}
+
+func TestProtect(t *testing.T) {
+	defer Define("DEFAULT", "", false)
+
+	Define("secret", "foo,bar", false)
+
+	if Mask("foo") != "foo" || Mask("bar") != "bar" {
+		t.Fatal("protected tokens were masked")
+	}
+
+	if Mask("baz") == "baz" {
+		t.Fatal("baz was not masked")
+	}
+
+	if Unmask(Mask("baz")) != "baz" {
+		t.Fatal("failed to unmask baz")
+	}
+
+	Protect("baz")
+
+	if Mask("baz") != "baz" {
+		t.Fatal("baz was masked once protected")
+	}
+}
+
+func TestCollision(t *testing.T) {
+	defer Define("DEFAULT", "", false)
+
+	// with this key y ciphers to NaN
+	Define(string(rune(36372)), "", false)
+
+	if cipher("y", KEY) != "NaN" {
+		t.Fatal("expected y to cipher to NaN")
+	}
+
+	if Mask("y") == "NaN" {
+		t.Fatal("y masked to a reserved word")
+	}
+
+	if Unmask(Mask("y")) != "y" {
+		t.Fatal("failed to unmask y")
+	}
+
+	// the name x ciphers to is used unmasked in the project
+	Define("secret", "", false)
+	masked := Mask("x")
+	Reserve(masked)
+
+	if Mask("x") == masked {
+		t.Fatalf("x masked to the reserved %v", masked)
+	}
+
+	if Mask("x") != Mask("x") {
+		t.Fatal("collision not resolved deterministically")
+	}
+
+	if Unmask(Mask("x")) != "x" {
+		t.Fatal("failed to unmask x")
+	}
+
+	// protected names are left as is
+	Define("secret", masked, false)
+
+	if Mask("x") == masked {
+		t.Fatalf("x masked to the protected %v", masked)
+	}
+}
+
+func names(n int) []string {
+	var names []string
+	for i := 0; i < n; i++ {
+		names = append(names, fmt.Sprintf("name_%v", i))
+	}
+	return names
+}
+
+// a deeply nested environment where every name is defined at the root
+func BenchmarkGetBinding(b *testing.B) {
+	vars := names(5000)
+
+	env := New()
+	for _, v := range vars {
+		env.SetBinding(&Binding{Name: v, Kind: Global, Function: true})
+	}
+
+	for i := 0; i < 20; i++ {
+		env = Enclose(env)
+		env.SetBinding(&Binding{Name: fmt.Sprintf("local_%v", i), Kind: Local})
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, v := range vars {
+			env.GetBinding(v, true)
+			env.GetFunctionBinding(v)
+		}
+	}
+}
+
+func BenchmarkGetPath(b *testing.B) {
+	paths := names(5000)
+
+	env := New()
+	for _, p := range paths {
+		env.setPath(p)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, p := range paths {
+			env.GetPath(p)
+		}
+	}
+}
+
+func BenchmarkMask(b *testing.B) {
+	defer Define("DEFAULT", "", false)
+
+	Define("secret", strings.Join(names(1000), ","), false)
+	vars := names(5000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, v := range vars {
+			Mask(v)
+		}
+	}
+}
+
+func TestSuperBinding(t *testing.T) {
+	global := New()
+	global.SetBinding(&Binding{Name: "x", Kind: Global})
+
+	fn := Enclose(global)
+	fn.SetBinding(&Binding{Name: "x", Kind: Local})
+
+	closure := Enclose(fn)
+	closure.SetBinding(&Binding{Name: "x", Kind: Local})
+
+	// skips the closure's own x
+	if b := closure.GetSuperBinding("x"); b != fn.GetBinding("x", false) {
+		t.Fatal("expected x of the enclosing function")
+	}
+
+	if b := fn.GetSuperBinding("x"); b.Kind != Global {
+		t.Fatalf("expected global x, got %v", b.Kind)
+	}
+
+	if b := global.GetSuperBinding("x"); b.Kind != Global {
+		t.Fatalf("expected global x at the top level, got %v", b.Kind)
+	}
+
+	if b := closure.GetSuperBinding("y"); b != nil {
+		t.Fatal("y is not bound")
+	}
+}
+
+func TestFunctionBinding(t *testing.T) {
+	global := New()
+	global.SetBinding(&Binding{Name: "fn", Kind: Global, Function: true})
+	global.SetBinding(&Binding{Name: "c", Kind: Global, Function: true})
+
+	fn := Enclose(global)
+	fn.SetBinding(&Binding{Name: "fn", Kind: Parameter})
+	fn.SetBinding(&Binding{Name: "c", Kind: Local, Data: true})
+
+	// a parameter may be a function
+	if b := fn.GetFunctionBinding("fn"); b.Kind != Parameter {
+		t.Fatalf("expected parameter fn, got %v", b.Kind)
+	}
+
+	// c <- c(1, 2) is not a function
+	if b := fn.GetFunctionBinding("c"); b.Kind != Global {
+		t.Fatalf("expected global c, got %v", b.Kind)
+	}
+}
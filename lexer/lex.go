@@ -12,8 +12,9 @@ import (
 )
 
 type File struct {
-	Path       string
-	PathSlice  []string
+	Path      string
+	PathSlice []string
+	// path of the obfuscated file relative to the output
 	Obfuscated string
 	Content    []byte
 	Items      token.Items
@@ -39,7 +40,11 @@ const stringAlpha = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456
 const stringAlphaNum = stringAlpha + stringNumber
 const stringMathOp = "+-*/^"
 
-var exported regexp.Regexp = *regexp.MustCompile("\\@export")
+var exported regexp.Regexp = *regexp.MustCompile(`^#'\s*@export\s*$`)
+
+// roxygen tags that affect the build of a package,
+// other tags are documentation and are dropped with comments
+var roxygen = regexp.MustCompile(`^#'\s*@(export|exportS3Method|exportClass|exportMethod|exportPattern|import|importFrom|importClassesFrom|importMethodsFrom|useDynLib|include|S3method|rawNamespace|evalNamespace|noRd)(\s|$)`)
 
 func New(fl Files) *Lexer {
 	return &Lexer{
@@ -145,15 +150,18 @@ func (l *Lexer) backup() {
 	l.char -= l.width
 }
 
+// peek returns the nth rune ahead without consuming it,
+// backup only knows the width of the last rune so we
+// restore the cursor rather than backing up n times
 func (l *Lexer) peek(n int) rune {
+	pos, char, width := l.pos, l.char, l.width
+
 	var r rune
 	for i := 0; i < n; i++ {
 		r = l.next()
 	}
 
-	for i := 0; i < n; i++ {
-		l.backup()
-	}
+	l.pos, l.char, l.width = pos, char, width
 
 	return r
 }
@@ -172,8 +180,10 @@ func (l *Lexer) Run() {
 		l.Lex()
 
 		// remove the EOF
-		if i < len(l.Files)-1 {
-			l.Files[l.filePos].Items = l.Files[l.filePos].Items[:len(l.Files[l.filePos].Items)-1]
+		// a file that failed to lex has no EOF to remove
+		n := len(l.Files[l.filePos].Items)
+		if i < len(l.Files)-1 && n > 0 && l.Files[l.filePos].Items[n-1].Class == token.ItemEOF {
+			l.Files[l.filePos].Items = l.Files[l.filePos].Items[:n-1]
 		}
 
 		if len(l.Files[l.filePos].Items) > 0 && l.Files[l.filePos].Items[len(l.Files[l.filePos].Items)-1].Class != token.ItemEOF {
@@ -272,13 +282,21 @@ func lexDefault(l *Lexer) stateFn {
 		l.next()
 		l.next()
 		l.next()
+
+		// ...length(), ...elt(n), ...names()
+		if strings.IndexRune(stringAlpha, l.peek(1)) > -1 {
+			return lexIdentifier
+		}
+
 		l.emit(token.ItemThreeDot)
 		return lexDefault
 	}
 
+	// ..1, ..2 and ..cols of data.table
 	if r1 == '.' && r2 == '.' {
 		l.next()
 		l.next()
+		l.acceptRun(stringAlphaNum + "._")
 		l.emit(token.ItemDoubleDot)
 		return lexDefault
 	}
@@ -450,6 +468,12 @@ func lexDefault(l *Lexer) stateFn {
 		return lexDefault
 	}
 
+	if r1 == '@' {
+		l.next()
+		l.emit(token.ItemAt)
+		return lexDefault
+	}
+
 	if r1 == ',' {
 		l.next()
 		l.emit(token.ItemComma)
@@ -610,6 +634,11 @@ func lexComment(l *Lexer) stateFn {
 		return lexDefault
 	}
 
+	if roxygen.MatchString(l.token()) {
+		l.emit(token.ItemRoxygen)
+		return lexDefault
+	}
+
 	l.emit(token.ItemComment)
 
 	return lexDefault
@@ -618,12 +647,11 @@ func lexComment(l *Lexer) stateFn {
 func lexBacktick(l *Lexer) func(l *Lexer) stateFn {
 	r := l.peek(1)
 	for r != '`' && r != token.EOF {
-		r = l.next()
+		l.next()
+		r = l.peek(1)
 	}
-	l.backup()
 
 	if r == token.EOF {
-		l.next()
 		return l.errorf("expecting closing backtick, got %v", l.token())
 	}
 
@@ -92,6 +92,136 @@ foo(1, 2)
 	}
 }
 
+func TestSlot(t *testing.T) {
+	code := `x@name <- "a"
+`
+
+	l := NewTest(code)
+
+	l.Run()
+
+	tokens :=
+		[]token.ItemType{
+			token.ItemIdent,
+			token.ItemAt,
+			token.ItemIdent,
+			token.ItemAssign,
+			token.ItemDoubleQuote,
+		}
+
+	for i, token := range tokens {
+		actual := l.Files[0].Items[i].Class
+		if actual != token {
+			t.Fatalf(
+				"token %v expected `%v`, got `%v`",
+				i,
+				token,
+				actual,
+			)
+		}
+	}
+}
+
+func TestDots(t *testing.T) {
+	code := `..1 + ...length() + ...elt(2)
+`
+
+	l := NewTest(code)
+
+	l.Run()
+
+	tokens :=
+		[]token.ItemType{
+			token.ItemDoubleDot,
+			token.ItemPlus,
+			token.ItemIdent,
+		}
+
+	for i, token := range tokens {
+		actual := l.Files[0].Items[i].Class
+		if actual != token {
+			t.Fatalf(
+				"token %v expected `%v`, got `%v`",
+				i,
+				token,
+				actual,
+			)
+		}
+	}
+
+	if l.Files[0].Items[0].Value != "..1" || l.Files[0].Items[2].Value != "...length" {
+		t.Fatalf("expected ..1 and ...length, got %v", l.Files[0].Items[:3])
+	}
+}
+
+func TestDotDot(t *testing.T) {
+	code := `dt[, ..cols]
+`
+
+	l := NewTest(code)
+
+	l.Run()
+
+	tokens :=
+		[]token.ItemType{
+			token.ItemIdent,
+			token.ItemLeftSquare,
+			token.ItemComma,
+			token.ItemDoubleDot,
+			token.ItemRightSquare,
+		}
+
+	for i, token := range tokens {
+		actual := l.Files[0].Items[i].Class
+		if actual != token {
+			t.Fatalf(
+				"token %v expected `%v`, got `%v`",
+				i,
+				token,
+				actual,
+			)
+		}
+	}
+
+	if l.Files[0].Items[3].Value != "..cols" {
+		t.Fatalf("expected ..cols, got %v", l.Files[0].Items[3])
+	}
+}
+
+func TestRoxygen(t *testing.T) {
+	code := `#' Title
+#' @export
+#' @exportS3Method pkg::generic
+#' @param x value
+# @export
+`
+
+	l := NewTest(code)
+
+	l.Run()
+
+	tokens :=
+		[]token.ItemType{
+			token.ItemComment,
+			token.ItemExport,
+			token.ItemRoxygen,
+			token.ItemComment,
+			token.ItemComment,
+		}
+
+	for i, token := range tokens {
+		actual := l.Files[0].Items[i].Class
+		if actual != token {
+			t.Fatalf(
+				"token %v expected `%v`, got `%v`",
+				i,
+				token,
+				actual,
+			)
+		}
+	}
+}
+
 func TestReal(t *testing.T) {
 	code := `box::use(
     ambiorix[Ambiorix],
@@ -4,10 +4,10 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/devOpifex/obfuscator/binder"
 	"github.com/devOpifex/obfuscator/cli"
 	"github.com/devOpifex/obfuscator/environment"
 	"github.com/devOpifex/obfuscator/lexer"
-	"github.com/devOpifex/obfuscator/obfuscator"
 	"github.com/devOpifex/obfuscator/parser"
 	"github.com/devOpifex/obfuscator/transpiler"
 )
@@ -36,7 +36,9 @@ func main() {
 		license = ""
 	}
 
-	obfs := &obfs{ignore: c.Ignore}
+	desc := readDescription(*c.In)
+
+	obfs := &obfs{ignore: c.Ignore, pkg: desc != nil}
 	err := obfs.readDir(*c.In)
 
 	if err != nil {
@@ -62,11 +64,54 @@ func main() {
 	env := environment.New()
 	env.SetPaths(l.Files)
 
-	o := obfuscator.New(env, p.Files())
-	o.Run()
-	o.Run()
+	ns := readNamespace(*c.In)
+
+	b := binder.New(env, p.Files())
+	declareMethods(ns, b)
+	declareExports(ns, b)
+
+	if desc != nil {
+		declarePackage(ns, b)
+	}
+
+	if *c.Classes {
+		b.RenameClasses()
+	}
+
+	if *c.Members {
+		b.ProtectPublicMembers()
+	}
+
+	if *c.Elements {
+		b.RenameElements(c.ProtectElements)
+	}
+
+	if *c.Environments {
+		b.RenameEnvironments()
+	}
+
+	for _, fn := range c.Masking {
+		b.DataMasking(fn, 1)
+	}
+
+	b.Run()
+	b.Warnings().Print()
 
-	t := transpiler.New(env, p.Files())
+	t := transpiler.New(env, b, p.Files())
 	t.Run()
 	t.Write(*c.Out, license)
+
+	if desc != nil {
+		if err := copyPackage(*c.In, *c.Out, p.Files()); err != nil {
+			log.Fatal(err)
+		}
+
+		if err := writeDescription(desc, *c.In, *c.Out, p.Files()); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := writeNamespace(ns, b, *c.Out); err != nil {
+		log.Fatal(err)
+	}
 }
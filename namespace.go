@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/devOpifex/obfuscator/binder"
+	"github.com/devOpifex/obfuscator/namespace"
+)
+
+// readNamespace reads the NAMESPACE at the root of the input,
+// it returns nil if there is none.
+func readNamespace(root string) *namespace.Namespace {
+	fl, err := os.ReadFile(filepath.Join(root, "NAMESPACE"))
+
+	if err != nil {
+		return nil
+	}
+
+	return namespace.Parse(string(fl))
+}
+
+// declareMethods registers the S3 methods of the NAMESPACE
+// with the binder: S3method(generic, class)
+func declareMethods(ns *namespace.Namespace, b *binder.Binder) {
+	if ns == nil {
+		return
+	}
+
+	for _, d := range ns.Get("S3method") {
+		if len(d.Args) == 2 {
+			b.DeclareMethod(d.Arg(0), d.Arg(1))
+		}
+	}
+}
+
+// declareExports registers the functions exported by the NAMESPACE
+// with the binder: export(fn) and exportPattern("^[^\\.]")
+func declareExports(ns *namespace.Namespace, b *binder.Binder) {
+	if ns == nil {
+		return
+	}
+
+	for _, d := range ns.Get("export") {
+		for i := range d.Args {
+			b.Export(d.Arg(i))
+		}
+	}
+
+	for _, d := range ns.Get("exportPattern") {
+		for i := range d.Args {
+			b.ExportPattern(d.Arg(i))
+		}
+	}
+}
+
+// writeNamespace renames the symbols of the NAMESPACE
+// consistently with the obfuscated code.
+func writeNamespace(ns *namespace.Namespace, b *binder.Binder, out string) error {
+	if ns == nil {
+		return nil
+	}
+
+	b.RenameNamespace(ns)
+
+	if err := os.MkdirAll(out, 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(out, "NAMESPACE"), []byte(ns.String()), 0644)
+}
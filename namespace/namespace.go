@@ -0,0 +1,196 @@
+// Package namespace reads and rewrites the NAMESPACE file of an R package.
+//
+// Only the directives are parsed, e.g.: export(foo), S3method(print, foo),
+// everything else (comments, blank lines) is written back as is.
+package namespace
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Directive is a call in the NAMESPACE, e.g.: S3method(print, invoice)
+type Directive struct {
+	Name string
+	// arguments as written, including quotes if any
+	Args []string
+	// position of the directive in the source
+	start   int
+	end     int
+	changed bool
+}
+
+type Namespace struct {
+	source     string
+	Directives []*Directive
+}
+
+// Parse reads the directives of a NAMESPACE file.
+func Parse(source string) *Namespace {
+	ns := &Namespace{source: source}
+
+	for i := 0; i < len(source); {
+		switch c := source[i]; {
+		case c == '#':
+			for i < len(source) && source[i] != '\n' {
+				i++
+			}
+
+		case isNameStart(c):
+			start := i
+			for i < len(source) && isName(source[i]) {
+				i++
+			}
+
+			name := source[start:i]
+
+			j := i
+			for j < len(source) && (source[j] == ' ' || source[j] == '\t') {
+				j++
+			}
+
+			if j >= len(source) || source[j] != '(' {
+				continue
+			}
+
+			args, end := arguments(source, j)
+			ns.Directives = append(ns.Directives, &Directive{
+				Name:  name,
+				Args:  args,
+				start: start,
+				end:   end,
+			})
+			i = end
+
+		default:
+			i++
+		}
+	}
+
+	return ns
+}
+
+// arguments splits the arguments of the directive opening at i,
+// it returns the position after the closing parenthesis.
+func arguments(source string, i int) ([]string, int) {
+	var args []string
+	var quote byte
+	depth := 0
+	start := i + 1
+
+	for ; i < len(source); i++ {
+		c := source[i]
+
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch c {
+		case '"', '\'', '`':
+			quote = c
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				if arg := strings.TrimSpace(source[start:i]); arg != "" || len(args) > 0 {
+					args = append(args, arg)
+				}
+				return args, i + 1
+			}
+		case ',':
+			if depth == 1 {
+				args = append(args, strings.TrimSpace(source[start:i]))
+				start = i + 1
+			}
+		}
+	}
+
+	return args, len(source)
+}
+
+// Arg returns an argument without quotes.
+func (d *Directive) Arg(i int) string {
+	if i >= len(d.Args) {
+		return ""
+	}
+
+	return unquote(d.Args[i])
+}
+
+// SetArg replaces an argument, keeping its quotes.
+func (d *Directive) SetArg(i int, value string) {
+	if i >= len(d.Args) {
+		return
+	}
+
+	arg := d.Args[i]
+
+	if len(arg) > 1 && isQuote(arg[0]) && arg[len(arg)-1] == arg[0] {
+		value = string(arg[0]) + value + string(arg[0])
+	}
+
+	d.Args[i] = value
+	d.changed = true
+}
+
+func (d *Directive) String() string {
+	return d.Name + "(" + strings.Join(d.Args, ",") + ")"
+}
+
+// String returns the NAMESPACE with the directives rewritten,
+// directives left unchanged are written as in the source.
+func (ns *Namespace) String() string {
+	var out bytes.Buffer
+
+	pos := 0
+	for _, d := range ns.Directives {
+		if !d.changed {
+			continue
+		}
+
+		out.WriteString(ns.source[pos:d.start])
+		out.WriteString(d.String())
+		pos = d.end
+	}
+
+	out.WriteString(ns.source[pos:])
+
+	return out.String()
+}
+
+// Get returns the directives with the given name, e.g.: export
+func (ns *Namespace) Get(name string) []*Directive {
+	var directives []*Directive
+
+	for _, d := range ns.Directives {
+		if d.Name == name {
+			directives = append(directives, d)
+		}
+	}
+
+	return directives
+}
+
+func unquote(arg string) string {
+	if len(arg) > 1 && isQuote(arg[0]) && arg[len(arg)-1] == arg[0] {
+		return arg[1 : len(arg)-1]
+	}
+
+	return arg
+}
+
+func isQuote(c byte) bool {
+	return c == '"' || c == '\'' || c == '`'
+}
+
+func isNameStart(c byte) bool {
+	return c == '.' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isName(c byte) bool {
+	return isNameStart(c) || c == '_' || (c >= '0' && c <= '9')
+}
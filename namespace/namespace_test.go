@@ -0,0 +1,67 @@
+package namespace
+
+import "testing"
+
+const source = `# Generated by roxygen2: do not edit by hand
+
+export(create_invoice)
+S3method(print,invoice)
+S3method("format", "invoice", format_invoice)
+if (getRversion() >= "4.0.0") {
+  export(total)
+}
+useDynLib(billing, .registration = TRUE)
+`
+
+func TestParse(t *testing.T) {
+	ns := Parse(source)
+
+	s3 := ns.Get("S3method")
+
+	if len(s3) != 2 {
+		t.Fatalf("expected 2 S3method, got %v", len(s3))
+	}
+
+	if s3[1].Arg(0) != "format" || s3[1].Arg(2) != "format_invoice" {
+		t.Fatalf("unexpected arguments %v", s3[1].Args)
+	}
+
+	exports := ns.Get("export")
+
+	if len(exports) != 2 || exports[1].Arg(0) != "total" {
+		t.Fatalf("expected 2 exports, got %v", exports)
+	}
+
+	dyn := ns.Get("useDynLib")
+
+	if len(dyn) != 1 || dyn[0].Arg(1) != ".registration = TRUE" {
+		t.Fatalf("unexpected useDynLib %v", dyn)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	ns := Parse(source)
+
+	if ns.String() != source {
+		t.Fatalf("unchanged NAMESPACE differs:\n%v", ns.String())
+	}
+
+	s3 := ns.Get("S3method")
+	s3[0].SetArg(1, "xyz")
+	s3[1].SetArg(2, "abc")
+
+	expected := `# Generated by roxygen2: do not edit by hand
+
+export(create_invoice)
+S3method(print,xyz)
+S3method("format","invoice",abc)
+if (getRversion() >= "4.0.0") {
+  export(total)
+}
+useDynLib(billing, .registration = TRUE)
+`
+
+	if ns.String() != expected {
+		t.Fatalf("unexpected NAMESPACE:\n%v", ns.String())
+	}
+}
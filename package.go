@@ -0,0 +1,186 @@
+package main
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/devOpifex/obfuscator/binder"
+	"github.com/devOpifex/obfuscator/description"
+	"github.com/devOpifex/obfuscator/environment"
+	"github.com/devOpifex/obfuscator/lexer"
+	"github.com/devOpifex/obfuscator/namespace"
+)
+
+// readDescription reads the DESCRIPTION at the root of the input,
+// it returns nil if there is none: the input is not a package.
+func readDescription(root string) *description.Description {
+	fl, err := os.ReadFile(filepath.Join(root, "DESCRIPTION"))
+
+	if err != nil {
+		return nil
+	}
+
+	return description.Parse(string(fl))
+}
+
+// declarePackage keeps the names of what a package exports:
+// functions and objects, S4 classes and generics, S3 methods
+// of exported generics follow their generic.
+func declarePackage(ns *namespace.Namespace, b *binder.Binder) {
+	b.ProtectExports()
+
+	if ns == nil {
+		return
+	}
+
+	for _, name := range []string{"exportClasses", "exportMethods"} {
+		for _, d := range ns.Get(name) {
+			for i := range d.Args {
+				environment.Protect(d.Arg(i))
+			}
+		}
+	}
+}
+
+// copyPackage copies the files of the package that are not obfuscated,
+// e.g.: man/, tests/, the DESCRIPTION and the NAMESPACE are rewritten.
+func copyPackage(root, out string, files lexer.Files) error {
+	obfuscated := make(map[string]bool)
+	for _, f := range files {
+		obfuscated[filepath.Clean(f.Path)] = true
+	}
+
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			// the output may be within the package
+			if entry.Name() == ".git" || filepath.Clean(path) == filepath.Clean(out) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if rel == "NAMESPACE" || rel == "DESCRIPTION" || obfuscated[filepath.Clean(path)] {
+			return nil
+		}
+
+		return copyFile(path, filepath.Join(out, rel))
+	})
+}
+
+// writeDescription renames the files listed in the Collate fields,
+// without Collate R sources the files in alphabetical order
+// which masked names change: a Collate keeping the order is added.
+func writeDescription(desc *description.Description, root, out string, files lexer.Files) error {
+	renamed := make(map[string]string)
+	for _, f := range files {
+		rel, err := filepath.Rel(filepath.Join(root, "R"), f.Path)
+		obfuscated, err2 := filepath.Rel("R", f.Obfuscated)
+
+		if err != nil || err2 != nil {
+			continue
+		}
+
+		renamed[filepath.ToSlash(rel)] = filepath.ToSlash(obfuscated)
+	}
+
+	collated := false
+	for _, f := range desc.Fields {
+		// Collate, Collate.unix, Collate.windows
+		if f.Name != "Collate" && !strings.HasPrefix(f.Name, "Collate.") {
+			continue
+		}
+
+		collated = true
+		files := description.Split(f.Value)
+		for i, name := range files {
+			if r, ok := renamed[name]; ok {
+				files[i] = r
+			}
+		}
+
+		desc.Set(f.Name, description.Join(files))
+	}
+
+	if !collated {
+		entries, err := os.ReadDir(filepath.Join(root, "R"))
+
+		if err != nil {
+			return err
+		}
+
+		// entries are sorted by name, files skipped with -ignore keep theirs
+		var collate []string
+		for _, e := range entries {
+			if e.IsDir() || !isCode(e.Name()) {
+				continue
+			}
+
+			if r, ok := renamed[e.Name()]; ok {
+				collate = append(collate, r)
+				continue
+			}
+
+			collate = append(collate, e.Name())
+		}
+
+		if len(collate) > 1 {
+			desc.Set("Collate", description.Join(collate))
+		}
+	}
+
+	if err := os.MkdirAll(out, 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(out, "DESCRIPTION"), []byte(desc.String()), 0644)
+}
+
+// isCode returns whether R sources a file of R/
+func isCode(name string) bool {
+	switch filepath.Ext(name) {
+	case ".R", ".r", ".S", ".s", ".q":
+		return true
+	}
+
+	return false
+}
+
+func copyFile(from, to string) error {
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+
+	src, err := os.Open(from)
+
+	if err != nil {
+		return err
+	}
+
+	defer src.Close()
+
+	dst, err := os.Create(to)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+
+	return dst.Close()
+}
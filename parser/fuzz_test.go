@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/devOpifex/obfuscator/ast"
+
+	"github.com/devOpifex/obfuscator/lexer"
+)
+
+func FuzzParse(f *testing.F) {
+	seeds := []string{
+		"x <- 1",
+		"foo <- function(x, y = 2) {\n  x + y\n}\nfoo(1, 2)",
+		"bar <- \\(x) {foo(x)}",
+		"if (x > 1) {y} else {z}",
+		"for (i in 1:10) {print(i)}",
+		"while (TRUE) {break}",
+		"x$y$z <- list(a = 1, b = c(1, 2))",
+		"box::use(pkg[fn], path/to/mod)",
+		"x[1, 2]; x[[\"a\"]]",
+		"`%>%` <- function(lhs, rhs) {rhs(lhs)}",
+		"f <- function(...) {..1}",
+		"#' @export\nfoo <- function() {NULL}",
+		"#' @rawNamespace if (TRUE) {\n#'   export(foo)\n#' }\n#' @noRd\nNULL",
+		"x <<- x + 1",
+		"'unterminated",
+		"`",
+		"%",
+		"f(",
+		"= function",
+		"for(",
+		"(function(x){x})(1)",
+	}
+
+	files, _ := filepath.Glob(filepath.Join("..", "test", "*.R"))
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		seeds = append(seeds, string(content))
+	}
+
+	for _, s := range seeds {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, code string) {
+		l := lexer.NewCode("fuzz.R", code)
+		l.Run()
+
+		p := New(l)
+		p.Run()
+	})
+}
+
+// files are lexed in sequence and all but the last EOF are dropped
+// so we also make sure that state does not leak across files
+func FuzzParseFiles(f *testing.F) {
+	f.Add("x <- 1", "y <- x")
+	f.Add("f <- function(", ")")
+	f.Add("'", "`")
+	f.Add("", "")
+
+	f.Fuzz(func(t *testing.T, first, second string) {
+		l := lexer.New(
+			lexer.Files{
+				{Path: "first.R", Content: []byte(first), Ast: &ast.Program{}},
+				{Path: "second.R", Content: []byte(second), Ast: &ast.Program{}},
+			},
+		)
+		l.Run()
+
+		p := New(l)
+		p.Run()
+	})
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/devOpifex/obfuscator/ast"
 	"github.com/devOpifex/obfuscator/diagnostics"
@@ -65,6 +66,7 @@ var precedences = map[token.ItemType]int{
 
 	// Special operators
 	token.ItemDollar:            DOLLAR,    // $
+	token.ItemAt:                DOLLAR,    // @
 	token.ItemNamespace:         NAMESPACE, // ::
 	token.ItemNamespaceInternal: NAMESPACE, // :::
 
@@ -162,6 +164,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.ItemGreaterOrEqual, p.parseInfixExpression)
 	p.registerInfix(token.ItemPipe, p.parseInfixExpression)
 	p.registerInfix(token.ItemDollar, p.parseInfixExpression)
+	p.registerInfix(token.ItemAt, p.parseInfixExpression)
 	p.registerInfix(token.ItemColon, p.parseInfixExpression)
 	p.registerInfix(token.ItemNamespace, p.parseInfixExpression)
 	p.registerInfix(token.ItemNamespaceInternal, p.parseInfixExpression)
@@ -286,12 +289,27 @@ func (p *Parser) noPrefixParseFnError(t token.ItemType) {
 	)
 }
 
+// an operator was found without a left hand side
+// e.g.: a call on an expression that failed to parse
+func (p *Parser) noOperandError() {
+	msg := fmt.Sprintf(
+		"expected expression before `%v`",
+		p.curToken.Value,
+	)
+	p.errors = append(
+		p.errors,
+		diagnostics.NewError(p.curToken, msg),
+	)
+}
+
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Class {
 	case token.ItemComment:
 		return p.parseCommentStatement()
 	case token.ItemExport:
 		return p.parseExportStatement()
+	case token.ItemRoxygen:
+		return p.parseRoxygenStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -324,7 +342,7 @@ func (p *Parser) parseFor() ast.Expression {
 		p.nextToken()
 	}
 
-	if !p.curTokenIs(token.ItemRightParen) {
+	if !p.expectCurrent(token.ItemRightParen) {
 		return nil
 	}
 
@@ -386,10 +404,11 @@ func (p *Parser) parseElipsis() ast.Expression {
 	return &ast.Keyword{Token: p.curToken, Value: "..."}
 }
 
+// parseDoubleDot parses ..1, ..2, the elements of dots
 func (p *Parser) parseDoubleDot() ast.Expression {
 	return &ast.Keyword{
 		Token: p.curToken,
-		Value: "..",
+		Value: p.curToken.Value,
 	}
 }
 
@@ -493,6 +512,10 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 
 	leftExp := prefix()
 
+	if leftExp == nil {
+		return nil
+	}
+
 	for !p.peekTokenIs(token.ItemEOF) &&
 		precedence < p.peekPrecedence() {
 		infix := p.infixParseFns[p.peekToken.Class]
@@ -565,6 +588,44 @@ func (p *Parser) parseExportStatement() ast.Statement {
 	return &ast.ExportStatement{Token: p.curToken, Value: p.curToken.Value}
 }
 
+// parseRoxygenStatement parses a roxygen tag and the lines of text
+// following it: the value of a tag runs until the next tag.
+func (p *Parser) parseRoxygenStatement() ast.Statement {
+	stmt := &ast.RoxygenStatement{Token: p.curToken}
+
+	text := strings.TrimPrefix(roxygenText(p.curToken.Value), "@")
+	stmt.Tag = text
+
+	if i := strings.IndexAny(text, " \t"); i >= 0 {
+		stmt.Tag, text = text[:i], text[i:]
+	} else {
+		text = ""
+	}
+
+	if text = strings.TrimSpace(text); text != "" {
+		stmt.Lines = append(stmt.Lines, text)
+	}
+
+	// @noRd takes no value
+	for stmt.Tag != "noRd" && p.peekTokenIs(token.ItemComment) {
+		text := roxygenText(p.peekToken.Value)
+
+		if !strings.HasPrefix(p.peekToken.Value, "#'") || text == "" || strings.HasPrefix(text, "@") {
+			break
+		}
+
+		stmt.Lines = append(stmt.Lines, text)
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// roxygenText returns the text of a roxygen comment: #' text
+func roxygenText(comment string) string {
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(comment), "#'"))
+}
+
 func (p *Parser) parseStringLiteral() ast.Expression {
 	str := &ast.StringLiteral{
 		Token: p.curToken,
@@ -620,7 +681,8 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	// it's a function declaration (bit hacky)
-	if p.curTokenIs(token.ItemAssign) && (p.peekTokenIs(token.ItemFunction) || p.peekTokenIs(token.ItemBackslash)) {
+	// x$fn <- function() {} is a plain assignment of an anonymous function
+	if p.curTokenIs(token.ItemAssign) && isFunctionName(left) && (p.peekTokenIs(token.ItemFunction) || p.peekTokenIs(token.ItemBackslash)) {
 		return p.parseNamedFunctionLiteral(left)
 	}
 
@@ -639,6 +701,15 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+func isFunctionName(left ast.Expression) bool {
+	switch left.(type) {
+	case *ast.Identifier, *ast.BacktickLiteral:
+		return true
+	}
+
+	return false
+}
+
 func (p *Parser) parseGroupedExpression() ast.Expression {
 	// skip paren left (
 	p.nextToken()
@@ -812,6 +883,11 @@ func (p *Parser) parseFunctionArguments() []*ast.Argument {
 }
 
 func (p *Parser) parseNamedFunctionLiteral(name ast.Expression) ast.Expression {
+	if name == nil {
+		p.noOperandError()
+		return nil
+	}
+
 	lit := &ast.FunctionLiteral{Token: p.curToken, Name: name.String()}
 
 	if !(p.peekTokenIs(token.ItemFunction) || p.peekTokenIs(token.ItemBackslash)) {
@@ -860,11 +936,16 @@ func (p *Parser) parseSquare() ast.Expression {
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	if function == nil {
+		p.noOperandError()
+		return nil
+	}
+
 	exp := &ast.CallExpression{Token: p.curToken, Name: function.Item().Value}
 
 	exp.Arguments = p.parseFunctionParameters()
 
-	if !p.curTokenIs(token.ItemRightParen) {
+	if !p.expectCurrent(token.ItemRightParen) {
 		return nil
 	}
 
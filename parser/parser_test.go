@@ -3,6 +3,7 @@ package parser
 import (
 	"testing"
 
+	"github.com/devOpifex/obfuscator/ast"
 	"github.com/devOpifex/obfuscator/lexer"
 )
 
@@ -28,3 +29,40 @@ func TestBasic(t *testing.T) {
 
 	p.Print()
 }
+
+func TestRoxygen(t *testing.T) {
+	code := `#' @importFrom stats median
+#'   quantile
+#' @param x value
+#' @noRd
+#' internal
+f <- function(x) {
+  median(x)
+}
+`
+
+	l := lexer.NewTest(code)
+
+	l.Run()
+	p := New(l)
+
+	p.Run()
+
+	statements := p.Files()[0].Ast.Statements
+
+	tag, ok := statements[0].(*ast.RoxygenStatement)
+
+	if !ok || tag.Tag != "importFrom" || len(tag.Lines) != 2 || tag.Lines[1] != "quantile" {
+		t.Fatalf("unexpected tag %v", statements[0])
+	}
+
+	if _, ok := statements[1].(*ast.CommentStatement); !ok {
+		t.Fatalf("expected @param to be a comment, got %v", statements[1])
+	}
+
+	noRd, ok := statements[2].(*ast.RoxygenStatement)
+
+	if !ok || noRd.Tag != "noRd" || len(noRd.Lines) != 0 {
+		t.Fatalf("unexpected tag %v", statements[2])
+	}
+}
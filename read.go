@@ -15,11 +15,20 @@ import (
 type obfs struct {
 	files  lexer.Files
 	ignore []string
+	root   string
+	// only R/ of a package is obfuscated, see package.go
+	pkg bool
 }
 
 var ignoreRegex = regexp.MustCompile("^__")
 
 func (o *obfs) readDir(root string) error {
+	o.root = root
+
+	if o.pkg {
+		root = filepath.Join(root, "R")
+	}
+
 	err := filepath.WalkDir(root, o.walk)
 
 	if err != nil {
@@ -38,9 +47,8 @@ func (o *obfs) walk(path string, directory fs.DirEntry, err error) error {
 		return nil
 	}
 
-	ext := filepath.Ext(path)
-
-	if ext != ".R" {
+	// .S and .q files outside of R/ of a package are unlikely to be R code
+	if !isCode(path) || (!o.pkg && strings.ToLower(filepath.Ext(path)) != ".r") {
 		return nil
 	}
 
@@ -54,22 +62,9 @@ func (o *obfs) walk(path string, directory fs.DirEntry, err error) error {
 		return err
 	}
 
-	pathSplit := strings.Split(filepath.ToSlash(path), "/")
-	for i := range pathSplit {
-		if i == len(pathSplit)-1 {
-			pathSplit[i] = strings.ReplaceAll(pathSplit[i], ".R", "")
-		}
-
-		if ignoreRegex.MatchString(pathSplit[i]) {
-			continue
-		}
-
-		pathSplit[i] = environment.Mask(pathSplit[i])
-	}
-
 	rfl := lexer.File{
 		Path:       path,
-		Obfuscated: filepath.Join(pathSplit...) + ".R",
+		Obfuscated: o.obfuscatedPath(path),
 		PathSlice:  strings.Split(path, "/"),
 		Content:    fl,
 		Ast: &ast.Program{
@@ -82,6 +77,33 @@ func (o *obfs) walk(path string, directory fs.DirEntry, err error) error {
 	return nil
 }
 
+// obfuscatedPath masks the directories and the name of a file,
+// relative to the root, R/ of a package and the extension are kept.
+func (o *obfs) obfuscatedPath(path string) string {
+	rel, err := filepath.Rel(o.root, path)
+
+	if err != nil {
+		rel = path
+	}
+
+	ext := filepath.Ext(rel)
+	pathSplit := strings.Split(filepath.ToSlash(strings.TrimSuffix(rel, ext)), "/")
+	for i := range pathSplit {
+
+		if ignoreRegex.MatchString(pathSplit[i]) {
+			continue
+		}
+
+		if o.pkg && i == 0 {
+			continue
+		}
+
+		pathSplit[i] = environment.Mask(pathSplit[i])
+	}
+
+	return filepath.Join(pathSplit...) + ext
+}
+
 func (o *obfs) Ignore(path string) bool {
 	for _, ignore := range o.ignore {
 		if strings.Contains(path, ignore) {
@@ -7,6 +7,7 @@ import (
 var ItemName = map[ItemType]string{
 	ItemError:             "error",
 	ItemExport:            "export",
+	ItemRoxygen:           "roxygen",
 	ItemIdent:             "identifier",
 	ItemDoubleQuote:       "double quote",
 	ItemSingleQuote:       "single quote",
@@ -32,6 +33,7 @@ var ItemName = map[ItemType]string{
 	ItemGreaterOrEqual:    "greater or equal",
 	ItemBool:              "boolean",
 	ItemDollar:            "dollar sign",
+	ItemAt:                "at sign",
 	ItemComma:             "comma",
 	ItemColon:             "colon",
 	ItemQuestion:          "question mark",
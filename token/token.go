@@ -29,6 +29,9 @@ const (
 	// $
 	ItemDollar
 
+	// @
+	ItemAt
+
 	// backtick
 	ItemBacktick
 
@@ -118,6 +121,7 @@ const (
 	// comment
 	ItemComment
 	ItemExport
+	ItemRoxygen
 
 	// compare
 	ItemDoubleEqual
@@ -0,0 +1,84 @@
+package transpiler
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/devOpifex/obfuscator/ast"
+	"github.com/devOpifex/obfuscator/environment"
+	"github.com/devOpifex/obfuscator/namespace"
+)
+
+// directives of the NAMESPACE generated by roxygen tags
+var directives = map[string]string{
+	"export":         "export",
+	"S3method":       "S3method",
+	"exportS3Method": "S3method",
+	"exportClass":    "exportClasses",
+	"exportMethod":   "exportMethods",
+}
+
+// transpileRoxygen writes a roxygen tag that affects the build,
+// the symbols and files of the project it names are renamed.
+func (t *Transpiler) transpileRoxygen(node *ast.RoxygenStatement) {
+	lines := node.Lines
+	words := strings.Fields(strings.Join(lines, " "))
+
+	switch node.Tag {
+	// @include utils.R
+	case "include":
+		for i, w := range words {
+			words[i] = t.maskFile(w)
+		}
+		lines = []string{strings.Join(words, " ")}
+
+	// @rawNamespace S3method(print, invoice)
+	case "rawNamespace":
+		ns := namespace.Parse(strings.Join(lines, "\n"))
+		t.binder.RenameNamespace(ns)
+		lines = strings.Split(ns.String(), "\n")
+
+	default:
+		directive, ok := directives[node.Tag]
+
+		if !ok || len(words) == 0 {
+			break
+		}
+
+		ns := namespace.Parse(directive + "(" + strings.Join(words, ",") + ")")
+		t.binder.RenameNamespace(ns)
+		lines = []string{strings.Join(ns.Directives[0].Args, " ")}
+	}
+
+	code := "#' @" + node.Tag
+	for i, line := range lines {
+		if i == 0 {
+			code += " " + line
+			continue
+		}
+		code += "\n#' " + line
+	}
+
+	t.addLine(code)
+}
+
+// maskFile returns the name of a file of the project once renamed
+func (t *Transpiler) maskFile(name string) string {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+
+	if ext == "" || !t.env.GetPath(stem) || strings.HasPrefix(stem, "__") {
+		return name
+	}
+
+	return environment.Mask(stem) + ext
+}
+
+// addLine writes code on lines of its own, e.g.: roxygen tags
+func (t *Transpiler) addLine(code string) {
+	if n := len(t.code); n > 0 && !strings.HasSuffix(t.code[n-1], "\n") {
+		t.addCode("\n")
+	}
+
+	t.addCode(code + "\n")
+}
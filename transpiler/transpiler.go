@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/devOpifex/obfuscator/ast"
+	"github.com/devOpifex/obfuscator/binder"
 	"github.com/devOpifex/obfuscator/environment"
 	"github.com/devOpifex/obfuscator/lexer"
 	"github.com/devOpifex/obfuscator/token"
@@ -15,24 +16,22 @@ import (
 type Transpiler struct {
 	code          []string
 	env           *environment.Environment
+	binder        *binder.Binder
 	file          lexer.File
-	boxUse        bool
-	boxUsePath    bool
-	useMethod     bool
 	obfuscateNext bool
-	lastNamespace string
 }
 
 var startWithDot = regexp.MustCompile(`^\.`)
 
 type Transpilers []*Transpiler
 
-func New(env *environment.Environment, files lexer.Files) Transpilers {
+func New(env *environment.Environment, b *binder.Binder, files lexer.Files) Transpilers {
 	var ts Transpilers
 
 	for _, f := range files {
 		ts = append(ts, &Transpiler{
 			env:           env,
+			binder:        b,
 			file:          f,
 			obfuscateNext: true,
 		})
@@ -71,65 +70,50 @@ func (t *Transpiler) Transpile(node ast.Node) ast.Node {
 		t.addCode("}")
 
 	case *ast.Keyword:
+		// ..cols of a data.table refers to the variable cols
+		if b := t.binder.Node(node); b != nil {
+			t.addCode(".." + t.mask(b, strings.TrimPrefix(node.Value, "..")))
+			return node
+		}
+
 		t.addCode(node.Value)
 
 	case *ast.ExportStatement:
-		t.addCode("\n#' @export\n")
+		t.addLine("#' @export")
+
+	case *ast.RoxygenStatement:
+		t.transpileRoxygen(node)
 
 	case *ast.BlockStatement:
 		for _, s := range node.Statements {
 			t.Transpile(s)
-			if _, ok := s.(*ast.CommentStatement); ok {
+			switch s.(type) {
+			case *ast.CommentStatement, *ast.ExportStatement, *ast.RoxygenStatement:
 				continue
 			}
 			t.addCode(";")
 		}
 
 	case *ast.Identifier:
-		// handles box::use(.pool[poolClose])
-		// it should not be obfuscated
-		if t.inBoxUse() && !t.boxUsePath {
-			t.addCode(node.Value)
+		// component of a path of box::use(app/logic/utils)
+		if p, ok := t.binder.ModulePath(node); ok {
+			t.addCode(p)
 			return node
 		}
 
 		if !t.obfuscateNext {
-			t.addCode(node.Value)
+			t.addCode(t.maskMember(node, node.Value))
 			t.obfuscateNext = true
 			return node
 		}
 
-		if t.inBoxUse() {
-			if t.env.GetPath(node.Value) {
-				t.addCode(environment.Mask(node.Value))
-				return node
-			}
-
-			if t.env.GetFunction(node.Value) {
-				t.addCode(environment.Mask(node.Value))
-				return node
-			}
-
-			if t.env.GetVariable(node.Value, true) {
-				t.addCode(environment.Mask(node.Value))
-				return node
-			}
-
-			t.addCode(node.Value)
-			return node
-		}
-
-		if t.env.GetVariable(node.Value, true) {
-			t.addCode(environment.Mask(node.Value))
+		// column of a data-masking call, e.g.: filter(df, amount > 0)
+		if e := t.binder.Column(node); e != nil {
+			t.addCode(e.Mask())
 			return node
 		}
 
-		if t.env.GetFunction(node.Value) {
-			t.addCode(environment.Mask(node.Value))
-			return node
-		}
-
-		t.addCode(node.Value)
+		t.addCode(t.mask(t.binder.Node(node), node.Value))
 
 		return node
 
@@ -157,10 +141,48 @@ func (t *Transpiler) Transpile(node ast.Node) ast.Node {
 		t.addCode(node.Value)
 
 	case *ast.StringLiteral:
-		if t.useMethod {
-			node.Str = environment.Mask(node.Str)
-			t.useMethod = false
+		// generic of UseMethod or NextMethod
+		if g := t.binder.Dispatch(node); g != nil {
+			t.addCode(node.Token.Value + g.Mask() + node.Token.Value)
+			return node
+		}
+
+		// class defined in the project
+		if c := t.binder.StringClass(node); c != nil {
+			t.addCode(node.Token.Value + c.Mask() + node.Token.Value)
+			return node
+		}
+
+		// S4 class, generic or slot
+		if s := t.binder.S4String(node); s != nil {
+			t.addCode(node.Token.Value + s.Mask() + node.Token.Value)
+			return node
+		}
+
+		// member of a class, e.g.: Store$set("public", "load", fn)
+		if m := t.binder.MemberString(node); m != nil {
+			t.addCode(node.Token.Value + m.Mask() + node.Token.Value)
+			return node
 		}
+
+		// symbol looked up by name, e.g.: get("helper")
+		if b := t.binder.Lookup(node); b != nil {
+			t.addCode(node.Token.Value + t.mask(b, node.Str) + node.Token.Value)
+			return node
+		}
+
+		// member of an environment, e.g.: get("token", envir = .state)
+		if m := t.binder.EnvString(node); m != nil {
+			t.addCode(node.Token.Value + m.Mask() + node.Token.Value)
+			return node
+		}
+
+		// list element or column, e.g.: x[["timeout"]]
+		if e := t.binder.ElementString(node); e != nil {
+			t.addCode(node.Token.Value + e.Mask() + node.Token.Value)
+			return node
+		}
+
 		t.addCode(node.Token.Value + node.Str + node.Token.Value)
 
 	case *ast.BacktickLiteral:
@@ -172,24 +194,19 @@ func (t *Transpiler) Transpile(node ast.Node) ast.Node {
 
 	case *ast.For:
 		t.addCode("for(")
-		t.env = environment.Enclose(t.env)
-		t.env.SetVariable(node.Name)
-		t.addCode(environment.Mask(node.Name))
+		t.addCode(t.mask(t.binder.Node(node), node.Name))
 		t.addCode(" in ")
 		t.Transpile(node.Vector)
 		t.addCode("){")
 		t.Transpile(node.Value)
 		t.addCode("}")
-		t.env = environment.Open(t.env)
 
 	case *ast.While:
-		t.env = environment.Enclose(t.env)
 		t.addCode("while(")
 		t.Transpile(node.Statement)
 		t.addCode("){")
 		t.Transpile(node.Value)
 		t.addCode("}")
-		t.env = environment.Open(t.env)
 
 	case *ast.InfixExpression:
 		if node.Operator == "<-" {
@@ -200,26 +217,14 @@ func (t *Transpiler) Transpile(node ast.Node) ast.Node {
 			node.Operator = " in "
 		}
 
-		if t.inBoxUse() && node.Operator == "/" {
-			t.boxUsePath = true
-		}
-
 		// it's a pipe e.g.: %>%
 		if strings.Contains(node.Operator, "%") {
 			node.Operator = " " + node.Operator + " "
 		}
 
-		if _, ok := node.Left.(*ast.Identifier); ok && node.Operator == "=" {
-			t.env.SetVariable(node.Left.String())
-		}
-
-		if _, ok := node.Left.(*ast.Identifier); ok && node.Operator == "::" {
-			t.lastNamespace = node.Left.String()
-		}
-
 		t.Transpile(node.Left)
 
-		if node.Operator == "$" {
+		if node.Operator == "$" || node.Operator == "@" {
 			t.obfuscateNext = false
 		}
 
@@ -231,7 +236,6 @@ func (t *Transpiler) Transpile(node ast.Node) ast.Node {
 		return node.Right
 
 	case *ast.Square:
-		t.boxUsePath = false
 		t.addCode(node.Token.Value)
 
 	case *ast.PostfixExpression:
@@ -242,16 +246,12 @@ func (t *Transpiler) Transpile(node ast.Node) ast.Node {
 		t.addCode("if(")
 		t.Transpile(node.Condition)
 		t.addCode("){")
-		t.env = environment.Enclose(t.env)
 		t.Transpile(node.Consequence)
-		t.env = environment.Open(t.env)
 		t.addCode("}")
 
 		if node.Alternative != nil {
 			t.addCode("else{")
-			t.env = environment.Enclose(t.env)
 			t.Transpile(node.Alternative)
-			t.env = environment.Open(t.env)
 			t.addCode("}")
 		}
 
@@ -266,12 +266,10 @@ func (t *Transpiler) Transpile(node ast.Node) ast.Node {
 			t.addCode(node.Name + "=")
 		}
 
-		t.env = environment.Enclose(t.env)
 		t.addCode("\\(")
 		for i, p := range node.Parameters {
 			if p.Name != "" && p.Name != "..." {
-				t.env.SetVariable(p.Name)
-				t.addCode(environment.Mask(p.Name))
+				t.addCode(t.mask(t.binder.Argument(p), p.Name))
 			}
 			if p.Name == "..." {
 				t.addCode("...")
@@ -289,7 +287,6 @@ func (t *Transpiler) Transpile(node ast.Node) ast.Node {
 			t.Transpile(node.Body)
 		}
 
-		t.env = environment.Open(t.env)
 		t.addCode("}")
 
 	case *ast.CallExpression:
@@ -307,7 +304,7 @@ func (t *Transpiler) obfuscateProgram(program *ast.Program) ast.Node {
 			continue
 		}
 		t.Transpile(statement)
-		if statement.Item().Class == token.ItemExport {
+		if statement.Item().Class == token.ItemExport || statement.Item().Class == token.ItemRoxygen {
 			continue
 		}
 		t.addCode(";")
@@ -317,33 +314,20 @@ func (t *Transpiler) obfuscateProgram(program *ast.Program) ast.Node {
 }
 
 func (t *Transpiler) transpileCallExpression(node *ast.CallExpression) {
-	// calls to box::use
-	if t.lastNamespace == "box" && node.Name == "use" {
-		t.setBoxUse()
-	}
-
-	if node.Name == "UseMethod" {
-		t.useMethod = true
-	}
-
-	ok := t.env.GetFunction(node.Name)
-	if ok && t.obfuscateNext {
-		t.addCode(environment.Mask(node.Name) + "(")
+	callee := t.binder.Node(node)
+	if t.obfuscateNext {
+		t.addCode(t.mask(callee, node.Name) + "(")
 	}
 
-	if !ok || !t.obfuscateNext {
-		t.addCode(node.Name + "(")
+	if !t.obfuscateNext {
+		t.addCode(t.maskMember(node, node.Name) + "(")
 	}
 
 	t.obfuscateNext = true
 
 	for i, a := range node.Arguments {
-		if a.Name != "" && ok {
-			t.addCode(environment.Mask(a.Name) + "=")
-		}
-
-		if a.Name != "" && !ok {
-			t.addCode(a.Name + "=")
+		if a.Name != "" {
+			t.addCode(t.maskArgument(a, callee) + "=")
 		}
 
 		if a.Value != nil {
@@ -353,7 +337,6 @@ func (t *Transpiler) transpileCallExpression(node *ast.CallExpression) {
 			}
 		}
 	}
-	t.unsetBoxUse()
 	t.addCode(")")
 }
 
@@ -378,41 +361,89 @@ func (t *Transpiler) addCode(code string) {
 	t.code = append(t.code, code)
 }
 
-func (t *Transpiler) setBoxUse() {
-	t.boxUse = true
-}
+// mask obfuscates a name if its binding is defined in the project
+func (t *Transpiler) mask(b *environment.Binding, name string) string {
+	if !b.Defined() || b.Protected {
+		return name
+	}
+
+	// S3 methods are renamed with their generic
+	if m := t.binder.Method(b); m != nil {
+		return m.Mask()
+	}
 
-func (t *Transpiler) unsetBoxUse() {
-	t.boxUse = false
+	// we don't obfuscate function names that start with a dot, e.g.: .onLoad
+	if b.Function && startWithDot.MatchString(name) {
+		return name
+	}
+
+	return environment.Mask(name)
 }
 
-func (t *Transpiler) inBoxUse() bool {
-	return t.boxUse
+// maskArgument obfuscates the name of a named argument,
+// it refers to a formal of the callee so we only obfuscate
+// it if the callee is defined in the project.
+func (t *Transpiler) maskArgument(a *ast.Argument, callee *environment.Binding) string {
+	// slot of an S4 class, e.g.: new("Person", name = "Alice")
+	if s := t.binder.SlotArgument(a); s != nil {
+		return s.Mask()
+	}
+
+	// member of a class, e.g.: R6Class(public = list(load = function(){}))
+	if m := t.binder.MemberArgument(a); m != nil {
+		return m.Mask()
+	}
+
+	// condition handler, e.g.: tryCatch(billing_error = function(e){})
+	if c := t.binder.HandlerClass(a); c != nil {
+		return c.Mask()
+	}
+
+	// list element or column, e.g.: list(timeout = 30)
+	if e := t.binder.ElementArgument(a); e != nil {
+		return e.Mask()
+	}
+
+	if formal := t.binder.Argument(a); formal != nil {
+		return t.mask(formal, a.Name)
+	}
+
+	// collected by dots, e.g.: list(...)
+	if t.binder.Passthrough(a) {
+		return a.Name
+	}
+
+	if !callee.Defined() {
+		return a.Name
+	}
+
+	return environment.Mask(a.Name)
 }
 
-func (t *Transpiler) transpileFunctionName(node *ast.FunctionLiteral) {
-	// we don't obfuscate function names that start with a dot, e.g.: .onLoad
-	if startWithDot.MatchString(node.Name) {
-		t.addCode(node.Name)
-		return
+// maskMember obfuscates the name on the right of $ or @
+// if it is a member or a slot of a class, or an element
+// of a list defined in the project.
+func (t *Transpiler) maskMember(node ast.Node, name string) string {
+	if m := t.binder.Member(node); m != nil {
+		return m.Mask()
 	}
 
-	// split on . to identify methods
-	split := strings.Split(node.Name, ".")
+	if s := t.binder.Slot(node); s != nil {
+		return s.Mask()
+	}
 
-	if len(split) < 2 {
-		t.env.SetFunction(node.Name)
-		t.addCode(environment.Mask(node.Name) + "=")
-		return
+	if m := t.binder.EnvMember(node); m != nil {
+		return m.Mask()
 	}
 
-	// it's a method (we find an existing function before first dot)
-	if ok := t.env.GetFunction(split[0]); ok {
-		rest := strings.Join(split[1:], ".")
-		t.addCode(environment.Mask(split[0]) + "." + rest + "=")
-		return
+	if e := t.binder.Element(node); e != nil {
+		return e.Mask()
 	}
 
-	t.env.SetFunction(node.Name)
-	t.addCode(environment.Mask(node.Name) + "=")
+	// variable of a data-masking call, e.g.: .env$threshold
+	return t.mask(t.binder.Node(node), name)
+}
+
+func (t *Transpiler) transpileFunctionName(node *ast.FunctionLiteral) {
+	t.addCode(t.mask(t.binder.Node(node), node.Name) + "=")
 }
@@ -1,11 +1,14 @@
 package transpiler
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/devOpifex/obfuscator/ast"
+	"github.com/devOpifex/obfuscator/binder"
 	"github.com/devOpifex/obfuscator/environment"
 	"github.com/devOpifex/obfuscator/lexer"
-	"github.com/devOpifex/obfuscator/obfuscator"
 	"github.com/devOpifex/obfuscator/parser"
 )
 
@@ -27,10 +30,774 @@ func TestBasic(t *testing.T) {
 	p.Run()
 
 	env := environment.New()
-	o := obfuscator.New(env, p.Files())
-	o.RunTwice()
 
-	trans := New(env, o.Files())
+	b := binder.New(env, p.Files())
+	b.Run()
+
+	trans := New(env, b, p.Files())
+	trans.Run()
+
+	if trans[0].GetCode() == "" {
+		t.Fatal("no code transpiled")
+	}
+}
+
+func transpile(t *testing.T, code string, options ...func(*binder.Binder)) string {
+	t.Helper()
+
+	l := lexer.NewTest(code)
+	l.Run()
+
+	p := parser.New(l)
+	p.Run()
+
+	if p.HasError() {
+		t.Fatal(p.Errors())
+	}
+
+	env := environment.New()
+
+	b := binder.New(env, p.Files())
+	for _, option := range options {
+		option(b)
+	}
+	b.Run()
+
+	trans := New(env, b, p.Files())
+	trans.Run()
+
+	return trans[0].GetCode()
+}
+
+func TestBindings(t *testing.T) {
+	code := `filter <- function(x) {
+  x
+}
+
+foo <- function() {
+  helper <- function() {
+    y
+  }
+  y <- 1
+  dplyr::filter(df, amount > 0)
+}`
+
+	got := transpile(t, code)
+
+	// y is used before it is assigned in the enclosing function
+	if strings.Contains(got, "{y;}") {
+		t.Fatalf("y was not obfuscated: %v", got)
+	}
+
+	// dplyr::filter is not the project's filter
+	if !strings.Contains(got, "dplyr::filter(df,amount>0x0)") {
+		t.Fatalf("dplyr::filter was obfuscated: %v", got)
+	}
+
+	if strings.Contains(got, "filter=") {
+		t.Fatalf("project filter was not obfuscated: %v", got)
+	}
+}
+
+// synthetic project of many files, all defining top level
+// variables and functions that are used across files
+func syntheticFiles(n int) lexer.Files {
+	var files lexer.Files
+	for i := 0; i < n; i++ {
+		code := fmt.Sprintf(`fn_%[1]v <- function(x, y = 1) {
+  total_%[1]v <- x + y
+  for (i in seq_len(total_%[1]v)) {
+    if (i > 2) {
+      total_%[1]v <- total_%[1]v + fn_%[2]v(i, y = y)
+    }
+  }
+  helper <- function(z) {
+    paste0(z, total_%[1]v)
+  }
+  helper(value_%[2]v)
+}
+
+value_%[1]v <- list(a = 1, b = c(1, 2, 3))
+result_%[1]v <- fn_%[1]v(value_%[1]v$a, y = length(value_%[2]v))
+`, i, (i+1)%n)
+		files = append(files, lexer.File{
+			Path:    fmt.Sprintf("R/file_%v.R", i),
+			Content: []byte(code),
+			Ast:     &ast.Program{},
+		})
+	}
+	return files
+}
+
+func BenchmarkTranspile(b *testing.B) {
+	files := syntheticFiles(2000)
+
+	l := lexer.New(files)
+	l.Run()
+
+	p := parser.New(l)
+	p.Run()
+
+	if p.HasError() {
+		b.Fatal(p.Errors())
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		env := environment.New()
+		env.SetPaths(p.Files())
+
+		bd := binder.New(env, p.Files())
+		bd.Run()
+
+		trans := New(env, bd, p.Files())
+		trans.Run()
+	}
+}
+
+func TestCallParameter(t *testing.T) {
+	code := `apply_fn <- function(fn, x) {
+  fn(x)
+}
+ui <- function(id) {
+  ns <- NS(id)
+  ns("button")
+}
+combine <- function(x) {
+  c <- c(1, 2)
+  c(c, x)
+}`
+
+	out := transpile(t, code)
+
+	fn := environment.Mask("fn")
+	ns := environment.Mask("ns")
+	c := environment.Mask("c")
+
+	for _, expected := range []string{
+		fn + "(",
+		ns + `("button")`,
+		"c(" + c + ",",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %v in %v", expected, out)
+		}
+	}
+}
+
+func TestSuperAssign(t *testing.T) {
+	code := `reset <- function() {
+  total <<- 0
+}
+
+report <- function() {
+  total
+}`
+
+	got := transpile(t, code)
+
+	if strings.Contains(got, "total") {
+		t.Fatalf("total assigned with <<- was not obfuscated: %v", got)
+	}
+}
+
+func TestS3(t *testing.T) {
+	code := `area <- function(shape) {
+  UseMethod("area")
+}
+
+area.square <- function(shape) {
+  shape$side * shape$side
+}
+
+format.square <- function(x, digits = 2, ...) {
+  NextMethod("format")
+}
+
+x <- area.square(sq)
+format(x, digits = 4)`
+
+	out := transpile(t, code)
+
+	area := environment.Mask("area")
+
+	for _, expected := range []string{
+		area + "=",
+		`UseMethod("` + area + `")`,
+		area + ".square=",
+		// formals of a method of a generic of another package
+		`format.square=\(x,digits=0x2,...)`,
+		"digits=0x4",
+		`NextMethod("format")`,
+		area + ".square(",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %v in %v", expected, out)
+		}
+	}
+}
+
+func TestR6(t *testing.T) {
+	code := `Store <- R6::R6Class("Store",
+  public = list(
+    items = NULL,
+    initialize = function(items = NULL) {
+      self$items <- items
+    },
+    add = function(x) {
+      self$items <- c(self$items, x)
+      private$log(x)
+    }
+  ),
+  private = list(
+    log = function(x) {
+      cat(x)
+    }
+  )
+)
+
+s <- Store$new(items = 1)
+s$add(1)$add(2)
+config <- list(add = TRUE)
+config$add`
+
+	out := transpile(t, code)
+
+	items := environment.Mask("items")
+	add := environment.Mask("add")
+	log := environment.Mask("log")
+
+	for _, expected := range []string{
+		items + "=NULL",
+		add + "=",
+		"self$" + items,
+		"private$" + log + "(",
+		log + "=",
+		"$new(" + items + "=0x1)",
+		"$" + add + "(0x1)$" + add + "(0x2)",
+		// not an object of the class
+		"$add;",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %v in %v", expected, out)
+		}
+	}
+}
+
+func TestR6Parameter(t *testing.T) {
+	code := `Store <- R6::R6Class("Store",
+  public = list(
+    load = function(x) {
+      private$cache <- x
+    }
+  ),
+  private = list(
+    cache = NULL
+  )
+)
+
+Account <- setRefClass("Account",
+  fields = list(balance = "numeric"),
+  methods = list(
+    deposit = function(x) {
+      balance <<- balance + x
+    },
+    refill = function() {
+      deposit(1)
+    }
+  )
+)
+
+use_store <- function(store, account) {
+  store$load(1)
+  account$deposit(2)
+}`
+
+	out := transpile(t, code)
+
+	cache := environment.Mask("cache")
+	balance := environment.Mask("balance")
+
+	// the class of store and account is unknown, their members keep their names
+	for _, expected := range []string{
+		"list(load=",
+		"$load(0x1)",
+		"private$" + cache,
+		"deposit=",
+		"{deposit(0x1);}",
+		"$deposit(0x2)",
+		balance + "=",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %v in %v", expected, out)
+		}
+	}
+}
+
+func TestDotDot(t *testing.T) {
+	code := `cols <- c("a", "b")
+dt <- data.table::data.table(a = 1, b = 2)
+dt[, ..cols]
+f <- function(...) {
+  ..1
+}`
+
+	out := transpile(t, code)
+
+	for _, expected := range []string{
+		"[,.." + environment.Mask("cols") + "]",
+		"{..1;}",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %v in %v", expected, out)
+		}
+	}
+}
+
+func TestS4(t *testing.T) {
+	code := `setClass("Person", representation(name = "character"))
+
+setMethod("show", "Person", function(object) {
+  cat(object@name)
+})
+
+p <- new("Person", name = "bob")
+p@name`
+
+	out := transpile(t, code)
+
+	person := environment.Mask("Person")
+	name := environment.Mask("name")
+
+	for _, expected := range []string{
+		`setClass("` + person + `",representation(` + name + `="character"))`,
+		`setMethod("show","` + person + `",\(object){cat(object@` + name + `);})`,
+		`new("` + person + `",` + name + `="bob")`,
+		"@" + name + ";",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %v in %v", expected, out)
+		}
+	}
+}
+
+func TestS4Signature(t *testing.T) {
+	code := `setGeneric("area", function(shape, ...) {
+  standardGeneric("area")
+})
+
+setMethod("area", signature(shape = "Circle"), function(shape, ...) {
+  shape@r
+})`
+
+	out := transpile(t, code)
+
+	area := environment.Mask("area")
+	shape := environment.Mask("shape")
+
+	for _, expected := range []string{
+		`setGeneric("` + area + `",\(` + shape + `,...)`,
+		`setMethod("` + area + `",signature(` + shape + `="Circle"),\(` + shape + `,...)`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %v in %v", expected, out)
+		}
+	}
+}
+
+func TestRefClass(t *testing.T) {
+	code := `Account <- setRefClass("Account",
+  fields = list(balance = "numeric"),
+  methods = list(
+    initialize = function(...) {
+      initFields(balance = 0)
+      callSuper(...)
+    },
+    deposit = function(x) {
+      balance <<- balance + x
+      invisible(.self)
+    }
+  )
+)
+
+a <- Account$new(balance = 1)
+a$deposit(2)
+a$field("balance")`
+
+	out := transpile(t, code)
+
+	balance := environment.Mask("balance")
+	deposit := environment.Mask("deposit")
+
+	for _, expected := range []string{
+		"fields=list(" + balance + "=",
+		deposit + "=",
+		balance + "<<-" + balance + "+",
+		"invisible(.self)",
+		"$new(" + balance + "=",
+		"$" + deposit + "(",
+		"initFields(" + balance + "=0x0)",
+		`$field("` + balance + `")`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %v in %v", expected, out)
+		}
+	}
+}
+
+func TestElements(t *testing.T) {
+	code := `config <- list(timeout = 30, id = 1)
+config$verbose <- TRUE
+config[["depth"]] <- 2
+if ("verbose" %in% names(config)) {
+  print(config$timeout + config[["depth"]] + config$id)
+}
+res <- fetch()
+res$status`
+
+	out := transpile(t, code, func(b *binder.Binder) {
+		b.RenameElements([]string{"id"})
+	})
+
+	timeout := environment.Mask("timeout")
+	verbose := environment.Mask("verbose")
+	depth := environment.Mask("depth")
+
+	for _, expected := range []string{
+		"list(" + timeout + "=0x1e,id=0x1)",
+		"$" + verbose + "=T",
+		`[["` + depth + `"]]=0x2`,
+		`"` + verbose + `" %in% names(`,
+		"$" + timeout + "+",
+		`[["` + depth + `"]]+`,
+		"$id)",
+		"$status",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %v in %v", expected, out)
+		}
+	}
+}
+
+func TestDataMasking(t *testing.T) {
+	code := `threshold <- 1
+df <- data.frame(amount = 1)
+df |> dplyr::filter(amount > !!threshold, amount < .env$threshold)`
+
+	out := transpile(t, code, func(b *binder.Binder) {
+		b.RenameElements([]string{})
+	})
+
+	threshold := environment.Mask("threshold")
+	amount := environment.Mask("amount")
+
+	expected := "dplyr::filter(" + amount + ">!!" + threshold + "," + amount + "<.env$" + threshold + ")"
+
+	if !strings.Contains(out, expected) {
+		t.Fatalf("expected %v in %v", expected, out)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	code := `helper <- function(x) {
+  x + 1
+}
+assign("counter", 0)
+f <- match.fun("helper")
+do.call("helper", list(counter))
+do.call("helper", list(x = 1))
+get(paste0("help", "er"))
+reset <- function() {
+  assign("total", 0, envir = .GlobalEnv)
+  assign("hits", 0, envir = globalenv())
+}
+total + hits`
+
+	out := transpile(t, code)
+
+	helper := environment.Mask("helper")
+	counter := environment.Mask("counter")
+	total := environment.Mask("total")
+	hits := environment.Mask("hits")
+
+	for _, expected := range []string{
+		`assign("` + counter + `",0x0)`,
+		`assign("` + total + `",0x0,envir=.GlobalEnv)`,
+		`assign("` + hits + `",0x0,envir=globalenv())`,
+		total + "+" + hits,
+		`match.fun("` + helper + `")`,
+		`do.call("` + helper + `",list(` + counter + `))`,
+		`do.call("` + helper + `",list(` + environment.Mask("x") + `=0x1))`,
+		`get(paste0("help","er"))`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %v in %v", expected, out)
+		}
+	}
+}
+
+func TestExports(t *testing.T) {
+	code := `#' @export
+summarise_data <- function(data, n = 1) {
+  helper(data = data)
+}
+
+helper <- function(data) {
+  data
+}
+
+summarise_data(data = 1)`
+
+	out := transpile(t, code)
+
+	data := environment.Mask("data")
+	helper := environment.Mask("helper")
+
+	for _, expected := range []string{
+		`\(data,n=0x1){` + helper + `(` + data + `=data);}`,
+		helper + `=\(` + data + `){` + data + `;}`,
+		`(data=0x1)`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %v in %v", expected, out)
+		}
+	}
+}
+
+func TestProtectExports(t *testing.T) {
+	defer environment.Define("DEFAULT", "", false)
+
+	code := `summarise_data <- function(data) {
+  UseMethod("summarise_data")
+}
+
+summarise_data.default <- function(data) {
+  helper(data)
+}
+
+summary.report <- function(object, ...) {
+  object
+}
+
+helper <- function(data) {
+  data
+}`
+
+	out := transpile(t, code, func(b *binder.Binder) {
+		b.Export("summarise_data")
+		b.DeclareMethod("summary", "report")
+		b.ProtectExports()
+	})
+
+	helper := environment.Mask("helper")
+
+	for _, expected := range []string{
+		`summarise_data=\(data){UseMethod("summarise_data");}`,
+		`summarise_data.default=\(data){` + helper + `(data);}`,
+		`summary.report=\(object,...){object;}`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %v in %v", expected, out)
+		}
+	}
+}
+
+func TestRoxygen(t *testing.T) {
+	code := `#' Format
+#'
+#' @param x report
+#' @export internal_fmt
+#' @importFrom stats median
+#' @exportS3Method pkg::generic
+generic.report <- function(x) {
+  internal_fmt(x)
+}
+
+internal_fmt <- function(x) {
+  median(x)
+}
+
+#' @rawNamespace S3method(format, report, internal_fmt)
+NULL`
+
+	out := transpile(t, code)
+
+	internal := environment.Mask("internal_fmt")
+
+	for _, expected := range []string{
+		"#' @export " + internal + "\n#' @importFrom stats median\n#' @exportS3Method pkg::generic\ngeneric.report=",
+		"#' @rawNamespace S3method(format,report," + internal + ")\nNULL",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %v in %v", expected, out)
+		}
+	}
+
+	if strings.Contains(out, "@param") || strings.Contains(out, "Format") {
+		t.Fatalf("expected documentation to be dropped in %v", out)
+	}
+}
+
+func TestClosures(t *testing.T) {
+	code := `make_adder <- function(n) {
+  function(x) {
+    x + n
+  }
+}
+
+add_two <- make_adder(2)
+add_two(40)`
+
+	out := transpile(t, code)
+
+	add := environment.Mask("add_two")
+
+	for _, expected := range []string{
+		add + "=",
+		add + "(0x28)",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %v in %v", expected, out)
+		}
+	}
+}
+
+func TestDots(t *testing.T) {
+	code := `inner <- function(alpha) {
+  alpha
+}
+wrapper <- function(...) {
+  inner(...)
+  c(..1, ...length(), ...elt(1))
+}
+joiner <- function(...) {
+  paste(...)
+}
+wrapper(alpha = 1)
+joiner("a", sep = "-")`
+
+	out := transpile(t, code)
+
+	alpha := environment.Mask("alpha")
+
+	for _, expected := range []string{
+		"(" + alpha + "=0x1)",
+		"c(..1,...length(),...elt(0x1))",
+		`("a",sep="-")`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %v in %v", expected, out)
+		}
+	}
+}
+
+func TestEnvironments(t *testing.T) {
+	code := `.state <- new.env()
+.state$token <- "abc"
+get("token", envir = .state)
+token <- 1`
+
+	token := environment.Mask("token")
+
+	out := transpile(t, code)
+
+	for _, expected := range []string{
+		`$token="abc"`,
+		`get("token",envir=`,
+		token + "=0x1",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %v in %v", expected, out)
+		}
+	}
+
+	out = transpile(t, code, func(b *binder.Binder) {
+		b.RenameEnvironments()
+	})
+
+	for _, expected := range []string{
+		"$" + token + `="abc"`,
+		`get("` + token + `",envir=`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %v in %v", expected, out)
+		}
+	}
+}
+
+func TestBox(t *testing.T) {
+	files := lexer.Files{
+		{
+			Path:       "app/main.R",
+			Obfuscated: "app/main.R",
+			Content: []byte(`box::use(
+  app/logic/utils[calc = compute],
+  u = app/logic/utils,
+  ./view/page,
+  dplyr[filter],
+  dp = dplyr,
+)
+filter(df, amount > 0)
+dp$select(df, amount)
+page$render(u$compute(1), calc(2))`),
+			Ast: &ast.Program{},
+		},
+		{
+			Path:       "app/logic/utils.R",
+			Obfuscated: "app/logic/" + environment.Mask("utils") + ".R",
+			Content: []byte(`compute <- function(n) { n }
+filter <- function(x) { x }`),
+			Ast: &ast.Program{},
+		},
+		{
+			Path:       "app/view/page.R",
+			Obfuscated: "app/view/page.R",
+			Content: []byte(`render <- function(x) { x }
+box::export(render)`),
+			Ast: &ast.Program{},
+		},
+	}
+
+	l := lexer.New(files)
+	l.Run()
+
+	p := parser.New(l)
+	p.Run()
+
+	if p.HasError() {
+		t.Fatal(p.Errors())
+	}
+
+	env := environment.New()
+
+	b := binder.New(env, p.Files())
+	b.Run()
+
+	trans := New(env, b, p.Files())
 	trans.Run()
-	trans.Write("newPath")
+
+	utils := environment.Mask("utils")
+	compute := environment.Mask("compute")
+	render := environment.Mask("render")
+	calc := environment.Mask("calc")
+	u := environment.Mask("u")
+
+	for _, expected := range []string{
+		"app/logic/" + utils + "[" + calc + "=" + compute + "]",
+		u + "=app/logic/" + utils,
+		"./view/page",
+		"dplyr[filter]",
+		"dp=dplyr",
+		"filter(df,amount>0x0)",
+		"dp$select(df,amount)",
+		"page$" + render + "(" + u + "$" + compute + "(0x1)," + calc + "(0x2))",
+	} {
+		if !strings.Contains(trans[0].GetCode(), expected) {
+			t.Fatalf("expected %v in %v", expected, trans[0].GetCode())
+		}
+	}
+
+	if !strings.Contains(trans[2].GetCode(), "box::export("+render+")") {
+		t.Fatalf("expected render to be exported: %v", trans[2].GetCode())
+	}
 }